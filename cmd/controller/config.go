@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/ratelimit"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// controllerConfig is the configuration of the controller given by the
+// flags.
+type controllerConfig struct {
+	threadiness  int
+	resyncPeriod time.Duration
+	// namespace is the namespace to watch, it is empty for all namespaces.
+	namespace string
+	// selector selects the TiDB clusters to manage.
+	selector    string
+	rateLimiter rateLimiterConfig
+}
+
+// rateLimiterConfig is the configuration of the rate limiter of the
+// workqueue. The retries of each cluster are delayed exponentially, and all
+// the retries are limited by a token bucket.
+type rateLimiterConfig struct {
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	qps       float64
+	burst     int
+}
+
+// validate checks the configuration before the controller is started.
+func (c *controllerConfig) validate() error {
+	if c.threadiness < 1 {
+		return fmt.Errorf("threadiness must be greater than or equal to 1, got %d", c.threadiness)
+	}
+	if c.resyncPeriod < 0 {
+		return fmt.Errorf("resync period must not be negative, got %v", c.resyncPeriod)
+	}
+	if _, err := labels.Parse(c.selector); err != nil {
+		return fmt.Errorf("invalid selector %q: %v", c.selector, err)
+	}
+	r := c.rateLimiter
+	if r.baseDelay <= 0 || r.maxDelay < r.baseDelay {
+		return fmt.Errorf("workqueue delays must satisfy 0 < base delay <= max delay, got %v and %v", r.baseDelay, r.maxDelay)
+	}
+	if r.qps <= 0 || r.burst < 1 {
+		return fmt.Errorf("workqueue qps and burst must be positive, got %v and %d", r.qps, r.burst)
+	}
+	return nil
+}
+
+// tweakListOptions scopes the TiDB informers to the selected clusters.
+func (c *controllerConfig) tweakListOptions(options *metav1.ListOptions) {
+	options.LabelSelector = c.selector
+}
+
+// newRateLimiter returns the rate limiter of the workqueue, it is the same
+// as workqueue.DefaultControllerRateLimiter with the default flags.
+func (r *rateLimiterConfig) newRateLimiter() workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(r.baseDelay, r.maxDelay),
+		&workqueue.BucketRateLimiter{Bucket: ratelimit.NewBucketWithRate(r.qps, int64(r.burst))},
+	)
+}
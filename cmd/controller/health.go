@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/client-go/tools/leaderelection"
+
+	"github.com/gaocegege/kubetidb/pkg/controller"
+)
+
+const (
+	healthzPath = "/healthz"
+	readyzPath  = "/readyz"
+)
+
+// healthCheck is a named check, the message is reported as well when the
+// check passes.
+type healthCheck struct {
+	name  string
+	check func() (string, error)
+}
+
+// healthChecks provides the liveness and readiness checks of the controller
+// process. The elector is nil if the leader election is disabled.
+type healthChecks struct {
+	controller  *controller.Controller
+	elector     *leaderelection.LeaderElector
+	syncTimeout time.Duration
+}
+
+// liveness reports the process is wedged if some worker is stuck in a sync,
+// Kubernetes restarts it then.
+func (h *healthChecks) liveness() []healthCheck {
+	return []healthCheck{
+		{name: "workers", check: func() (string, error) {
+			if !h.controller.WorkersStarted() {
+				return "not started", nil
+			}
+			return "", h.controller.CheckWorkers(h.syncTimeout)
+		}},
+		{name: "leader", check: h.checkLeader},
+	}
+}
+
+// readiness reports the process is ready once the informer caches are
+// synced, and the workers are started if it is the leader.
+func (h *healthChecks) readiness() []healthCheck {
+	return []healthCheck{
+		{name: "informers", check: func() (string, error) {
+			if !h.controller.HasSynced() {
+				return "", fmt.Errorf("caches are not synced")
+			}
+			return "", nil
+		}},
+		{name: "workers", check: func() (string, error) {
+			if h.isLeader() && !h.controller.WorkersStarted() {
+				return "", fmt.Errorf("workers are not started")
+			}
+			return "", nil
+		}},
+		{name: "leader", check: h.checkLeader},
+	}
+}
+
+func (h *healthChecks) isLeader() bool {
+	return h.elector == nil || h.elector.IsLeader()
+}
+
+// checkLeader reports the leader status, a standby is healthy as well.
+func (h *healthChecks) checkLeader() (string, error) {
+	if h.elector == nil {
+		return "leader election disabled", nil
+	}
+	if h.elector.IsLeader() {
+		return "leading", nil
+	}
+	return fmt.Sprintf("standby, leader is %q", h.elector.GetLeader()), nil
+}
+
+// handler serves the result of the checks, one line per check, with 500 if
+// any of them fails.
+func handler(name string, checks func() []healthCheck) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		failed := false
+		for _, c := range checks() {
+			message, err := c.check()
+			switch {
+			case err != nil:
+				failed = true
+				fmt.Fprintf(&buf, "[-]%s failed: %v\n", c.name, err)
+			case message != "":
+				fmt.Fprintf(&buf, "[+]%s ok: %s\n", c.name, message)
+			default:
+				fmt.Fprintf(&buf, "[+]%s ok\n", c.name)
+			}
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if failed {
+			glog.Warningf("%s check failed:\n%s", name, buf.String())
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(&buf, "%s check failed\n", name)
+		} else {
+			fmt.Fprintf(&buf, "%s check passed\n", name)
+		}
+		w.Write(buf.Bytes())
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// leaderElectionLockName is the name of the object holding the lock.
+	leaderElectionLockName = "kubetidb-controller"
+	// leasesResourceLock is the Lease lock, it is not supported by the
+	// client-go in use.
+	leasesResourceLock = "leases"
+)
+
+// leaderElectionConfig is the configuration of the leader election given by
+// the flags.
+type leaderElectionConfig struct {
+	enabled       bool
+	resourceLock  string
+	namespace     string
+	identity      string
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+}
+
+// runWithLeaderElection runs the given function only if the controller is
+// elected as the leader. The process exits once the leadership is lost, so
+// that a standby could take over with clean caches. The elector is returned
+// to report the leader status.
+func runWithLeaderElection(kubeClient kubernetes.Interface, config leaderElectionConfig, run func(stopCh <-chan struct{})) (*leaderelection.LeaderElector, error) {
+	identity := config.identity
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get hostname: %v", err)
+		}
+		// The hostname is not unique if the controller runs out of the
+		// cluster, add a random suffix to distinguish the candidates.
+		identity = fmt.Sprintf("%s_%s", hostname, rand.String(8))
+	}
+	if config.resourceLock == leasesResourceLock {
+		return nil, fmt.Errorf("resource lock %s is not supported by this version of client-go, use %s or %s",
+			leasesResourceLock, resourcelock.ConfigMapsResourceLock, resourcelock.EndpointsResourceLock)
+	}
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartLogging(glog.Infof)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events(config.namespace)})
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: leaderElectionLockName})
+
+	lock, err := resourcelock.New(config.resourceLock, config.namespace, leaderElectionLockName, kubeClient.CoreV1(),
+		resourcelock.ResourceLockConfig{
+			Identity:      identity,
+			EventRecorder: recorder,
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: config.leaseDuration,
+		RenewDeadline: config.renewDeadline,
+		RetryPeriod:   config.retryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: run,
+			OnStoppedLeading: func() {
+				glog.Fatalf("Leader election lost")
+			},
+			OnNewLeader: func(identity string) {
+				glog.Infof("New leader elected: %s", identity)
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	glog.Infof("Starting leader election as %s", identity)
+	go elector.Run()
+	return elector, nil
+}
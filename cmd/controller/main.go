@@ -2,32 +2,55 @@ package main
 
 import (
 	"flag"
+	"net/http"
 	"os"
 	"runtime"
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 
 	clientset "github.com/gaocegege/kubetidb/pkg/clientset/versioned"
 	"github.com/gaocegege/kubetidb/pkg/controller"
 	tidbInformers "github.com/gaocegege/kubetidb/pkg/informers/externalversions"
+	// Register the workqueue metrics.
+	_ "github.com/gaocegege/kubetidb/pkg/util/metrics"
 	"github.com/gaocegege/kubetidb/pkg/util/signals"
 	"github.com/gaocegege/kubetidb/pkg/version"
 )
 
 var (
-	masterURL    string
-	printVersion bool
-	kubeconfig   string
+	masterURL          string
+	printVersion       bool
+	kubeconfig         string
+	listenAddress      string
+	healthzSyncTimeout time.Duration
+	controllerConf     controllerConfig
+	leaderElection     leaderElectionConfig
 )
 
 func run() {
 	// set up signals so we handle the first shutdown signal gracefully
 	stopCh := signals.SetupSignalHandler()
 
+	if err := controllerConf.validate(); err != nil {
+		glog.Fatalf("Error validating flags: %s", err.Error())
+	}
+	// Run the per-namespace controllers in their own namespaces, so that
+	// they do not contend for the same lock.
+	if leaderElection.namespace == "" {
+		leaderElection.namespace = controllerConf.namespace
+		if leaderElection.namespace == "" {
+			leaderElection.namespace = metav1.NamespaceDefault
+		}
+	}
+
 	cfg, err := clientcmd.BuildConfigFromFlags(masterURL, kubeconfig)
 	if err != nil {
 		glog.Fatalf("Error building kubeconfig: %s", err.Error())
@@ -43,24 +66,72 @@ func run() {
 		glog.Fatalf("Error building example clientset: %s", err.Error())
 	}
 
-	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, time.Second*30)
-	tidbInformerFactory := tidbInformers.NewSharedInformerFactory(tidbClient, time.Second*30)
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, controllerConf.resyncPeriod)
+	tidbInformerFactory := tidbInformers.NewFilteredSharedInformerFactory(tidbClient, controllerConf.resyncPeriod,
+		controllerConf.namespace, controllerConf.tweakListOptions)
 
-	controller := controller.NewController(kubeClient, tidbClient, kubeInformerFactory, tidbInformerFactory)
+	controller := controller.NewController(kubeClient, tidbClient, kubeInformerFactory, tidbInformerFactory,
+		controllerConf.namespace, controllerConf.rateLimiter.newRateLimiter())
+	prometheus.MustRegister(controller.Collector())
 
 	go kubeInformerFactory.Start(stopCh)
 	go tidbInformerFactory.Start(stopCh)
 
-	if err = controller.Run(2, stopCh); err != nil {
-		glog.Fatalf("Error running controller: %s", err.Error())
+	runController := func(<-chan struct{}) {
+		if err := controller.Run(controllerConf.threadiness, stopCh); err != nil {
+			glog.Fatalf("Error running controller: %s", err.Error())
+		}
 	}
+	health := &healthChecks{controller: controller, syncTimeout: healthzSyncTimeout}
+	if leaderElection.enabled {
+		if health.elector, err = runWithLeaderElection(kubeClient, leaderElection, runController); err != nil {
+			glog.Fatalf("Error running leader election: %s", err.Error())
+		}
+	}
+
+	// The standby candidates serve the metrics and health checks as well.
+	go serve(health)
 
+	if !leaderElection.enabled {
+		runController(stopCh)
+		return
+	}
+	<-stopCh
+}
+
+// serve serves the metrics and the health checks of the controller.
+func serve(health *healthChecks) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle(healthzPath, handler("healthz", health.liveness))
+	mux.Handle(readyzPath, handler("readyz", health.readiness))
+	glog.Infof("Serving metrics and health checks on %s", listenAddress)
+	if err := http.ListenAndServe(listenAddress, mux); err != nil {
+		glog.Fatalf("Error serving metrics and health checks: %s", err.Error())
+	}
 }
 
 func init() {
 	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
 	flag.StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
 	flag.BoolVar(&printVersion, "version", false, "Show version and quit")
+	flag.IntVar(&controllerConf.threadiness, "threadiness", 2, "The number of workers syncing the TiDB clusters concurrently.")
+	flag.DurationVar(&controllerConf.resyncPeriod, "resync-period", 30*time.Second, "The period the informers resync all the watched resources, 0 disables the resync.")
+	flag.StringVar(&controllerConf.namespace, "namespace", metav1.NamespaceAll, "The namespace to watch the TiDB clusters and their resources in. Default to all namespaces.")
+	flag.StringVar(&controllerConf.selector, "selector", "", "The label selector of the TiDB clusters to manage. Default to all clusters.")
+	flag.DurationVar(&controllerConf.rateLimiter.baseDelay, "workqueue-base-delay", 5*time.Millisecond, "The delay of the first retry of a failed sync, it doubles on every retry.")
+	flag.DurationVar(&controllerConf.rateLimiter.maxDelay, "workqueue-max-delay", 1000*time.Second, "The maximum delay of the retries of a failed sync.")
+	flag.Float64Var(&controllerConf.rateLimiter.qps, "workqueue-qps", 10, "The overall rate of the retries of the workqueue.")
+	flag.IntVar(&controllerConf.rateLimiter.burst, "workqueue-burst", 100, "The burst of the retries of the workqueue.")
+	flag.StringVar(&listenAddress, "listen-address", ":8080", "The address to serve the metrics and health checks on.")
+	flag.DurationVar(&healthzSyncTimeout, "healthz-sync-timeout", 5*time.Minute, "The duration a worker may spend syncing a cluster before the liveness check fails.")
+	flag.BoolVar(&leaderElection.enabled, "leader-elect", false, "Start a leader election client and gain leadership before running the controller. Enable this when running replicated controllers for high availability, the controller needs the permission to get, create and update the configmaps or endpoints used for locking.")
+	flag.StringVar(&leaderElection.resourceLock, "leader-elect-resource-lock", resourcelock.ConfigMapsResourceLock, "The type of the resource object used for locking, one of configmaps and endpoints.")
+	flag.StringVar(&leaderElection.namespace, "leader-elect-namespace", "", "The namespace of the resource object used for locking. Default to the watched namespace, or default if all namespaces are watched.")
+	flag.StringVar(&leaderElection.identity, "leader-elect-identity", "", "The identity of the leader election candidate. Default to the hostname with a random suffix.")
+	flag.DurationVar(&leaderElection.leaseDuration, "leader-elect-lease-duration", 15*time.Second, "The duration that non-leader candidates will wait after observing a leadership renewal until attempting to acquire leadership.")
+	flag.DurationVar(&leaderElection.renewDeadline, "leader-elect-renew-deadline", 10*time.Second, "The interval between attempts by the acting leader to renew its leadership before it stops leading. This must be less than the lease duration.")
+	flag.DurationVar(&leaderElection.retryPeriod, "leader-elect-retry-period", 2*time.Second, "The duration the candidates should wait between attempts of acquiring and renewing leadership.")
 }
 
 func main() {
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+
+	"github.com/golang/glog"
+
+	"github.com/gaocegege/kubetidb/pkg/util/signals"
+	"github.com/gaocegege/kubetidb/pkg/version"
+	"github.com/gaocegege/kubetidb/pkg/webhook"
+)
+
+var (
+	certFile     string
+	keyFile      string
+	port         int
+	printVersion bool
+)
+
+func run() {
+	// set up signals so we handle the first shutdown signal gracefully
+	stopCh := signals.SetupSignalHandler()
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: webhook.NewHandler(),
+		TLSConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		},
+	}
+	go func() {
+		<-stopCh
+		if err := server.Close(); err != nil {
+			glog.Errorf("Error closing webhook server: %s", err.Error())
+		}
+	}()
+
+	glog.Infof("Serving the admission webhook on %s", server.Addr)
+	if err := server.ListenAndServeTLS(certFile, keyFile); err != nil && err != http.ErrServerClosed {
+		glog.Fatalf("Error serving webhook: %s", err.Error())
+	}
+}
+
+func init() {
+	flag.StringVar(&certFile, "tls-cert-file", "", "Path to the x509 certificate for HTTPS.")
+	flag.StringVar(&keyFile, "tls-private-key-file", "", "Path to the x509 private key matching --tls-cert-file.")
+	flag.IntVar(&port, "port", 443, "The port to serve the admission webhook on.")
+	flag.BoolVar(&printVersion, "version", false, "Show version and quit")
+}
+
+func main() {
+	flag.Parse()
+
+	glog.Infof("kubetidb-webhook Version: %v", version.Version)
+	glog.Infof("Git SHA: %s", version.GitSHA)
+	glog.Infof("Go Version: %s", runtime.Version())
+	glog.Infof("Go OS/Arch: %s/%s", runtime.GOOS, runtime.GOARCH)
+	if printVersion {
+		os.Exit(0)
+	}
+	if certFile == "" || keyFile == "" {
+		glog.Fatalf("--tls-cert-file and --tls-private-key-file are required")
+	}
+	run()
+}
@@ -0,0 +1,513 @@
+// crd-gen generates the CustomResourceDefinition of TiDB. The validation
+// schema of the spec is derived from the Go types in the API package, and
+// the printer columns are read from the comments of the TiDB type.
+//
+// The fields could be annotated with the markers below:
+//
+//	// +kubetidb:validation:Minimum=1
+//	// +kubetidb:validation:Maximum=7
+//	// +kubetidb:validation:Pattern=^[a-z]+$
+//	// +kubetidb:validation:Enum=ClusterIP;NodePort;LoadBalancer
+//
+// and the TiDB type with:
+//
+//	// +kubetidb:printcolumn:name=Phase,type=string,JSONPath=.status.phase
+//	// +kubetidb:subresource:status
+//	// +kubetidb:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/glog"
+
+	"github.com/gaocegege/kubetidb/pkg/apis/tidb/v1alpha1"
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+	"github.com/gaocegege/kubetidb/pkg/webhook"
+)
+
+const (
+	header = "# Code generated by crd-gen. DO NOT EDIT.\n"
+
+	validationMarker  = "+kubetidb:validation:"
+	printColumnMarker = "+kubetidb:printcolumn:"
+	statusMarker      = "+kubetidb:subresource:status"
+	scaleMarker       = "+kubetidb:subresource:scale:"
+
+	// quantityPattern is the format of resource.Quantity.
+	quantityPattern = `^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$`
+)
+
+var (
+	inputDir         string
+	output           string
+	webhookNamespace string
+	webhookName      string
+)
+
+// externalTypes are the schemas of the types declared outside of the API
+// package. The Kubernetes types are validated by the controller when the
+// objects are created, so they are left as plain objects.
+var externalTypes = map[string]*schema{
+	"resource.Quantity":                   {Type: "string", Pattern: quantityPattern},
+	"metav1.Time":                         {Type: "string", Format: "date-time"},
+	"metav1.Duration":                     {Type: "string"},
+	"v1.ConditionStatus":                  {Type: "string"},
+	"v1.ServiceType":                      {Type: "string"},
+	"v1.ServiceExternalTrafficPolicyType": {Type: "string"},
+	"v1.PodTemplateSpec":                  {Type: "object"},
+	"v1.PersistentVolumeClaim":            {Type: "object"},
+}
+
+// schema is the subset of the OpenAPI v3 schema supported by
+// CustomResourceDefinition validation.
+type schema struct {
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *int64             `json:"minimum,omitempty"`
+	Maximum              *int64             `json:"maximum,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+}
+
+// printColumn is an additional printer column of kubectl get.
+type printColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	JSONPath string `json:"JSONPath"`
+}
+
+type scaleSubresource struct {
+	SpecReplicasPath   string `json:"specReplicasPath"`
+	StatusReplicasPath string `json:"statusReplicasPath"`
+	LabelSelectorPath  string `json:"labelSelectorPath,omitempty"`
+}
+
+type crdSubresources struct {
+	Status *struct{}         `json:"status,omitempty"`
+	Scale  *scaleSubresource `json:"scale,omitempty"`
+}
+
+type crdNames struct {
+	Kind       string   `json:"kind"`
+	Singular   string   `json:"singular"`
+	Plural     string   `json:"plural"`
+	ShortNames []string `json:"shortNames,omitempty"`
+}
+
+type crdVersion struct {
+	Name    string `json:"name"`
+	Served  bool   `json:"served"`
+	Storage bool   `json:"storage"`
+}
+
+type serviceReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Path      string `json:"path,omitempty"`
+}
+
+type webhookClientConfig struct {
+	Service  serviceReference `json:"service"`
+	CABundle string           `json:"caBundle"`
+}
+
+type crdConversion struct {
+	Strategy            string               `json:"strategy"`
+	WebhookClientConfig *webhookClientConfig `json:"webhookClientConfig,omitempty"`
+}
+
+type crdValidation struct {
+	OpenAPIV3Schema *schema `json:"openAPIV3Schema"`
+}
+
+type crdSpec struct {
+	Group                    string           `json:"group"`
+	Version                  string           `json:"version"`
+	Versions                 []crdVersion     `json:"versions"`
+	Names                    crdNames         `json:"names"`
+	Scope                    string           `json:"scope"`
+	Conversion               *crdConversion   `json:"conversion,omitempty"`
+	Subresources             *crdSubresources `json:"subresources,omitempty"`
+	Validation               *crdValidation   `json:"validation,omitempty"`
+	AdditionalPrinterColumns []printColumn    `json:"additionalPrinterColumns,omitempty"`
+}
+
+type crd struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   map[string]string `json:"metadata"`
+	Spec       crdSpec           `json:"spec"`
+}
+
+// generator resolves the schemas of the types declared in the API package.
+type generator struct {
+	types map[string]*ast.TypeSpec
+	docs  map[string]*ast.CommentGroup
+}
+
+func newGenerator(dir string) (*generator, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{
+		types: make(map[string]*ast.TypeSpec),
+		docs:  make(map[string]*ast.CommentGroup),
+	}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				genDecl, ok := decl.(*ast.GenDecl)
+				if !ok || genDecl.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range genDecl.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					g.types[typeSpec.Name.Name] = typeSpec
+					// The doc of a single type declaration is attached to
+					// the declaration instead of the type.
+					if typeSpec.Doc != nil {
+						g.docs[typeSpec.Name.Name] = typeSpec.Doc
+					} else {
+						g.docs[typeSpec.Name.Name] = genDecl.Doc
+					}
+				}
+			}
+		}
+	}
+	return g, nil
+}
+
+// schemaOf returns the schema of the type expression.
+func (g *generator) schemaOf(expr ast.Expr) (*schema, error) {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return g.schemaOf(t.X)
+	case *ast.ArrayType:
+		items, err := g.schemaOf(t.Elt)
+		if err != nil {
+			return nil, err
+		}
+		return &schema{Type: "array", Items: items}, nil
+	case *ast.MapType:
+		values, err := g.schemaOf(t.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &schema{Type: "object", AdditionalProperties: values}, nil
+	case *ast.SelectorExpr:
+		name := fmt.Sprintf("%s.%s", t.X.(*ast.Ident).Name, t.Sel.Name)
+		s, ok := externalTypes[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported external type %s", name)
+		}
+		copied := *s
+		return &copied, nil
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return &schema{Type: "string"}, nil
+		case "bool":
+			return &schema{Type: "boolean"}, nil
+		case "int32", "int64":
+			return &schema{Type: "integer", Format: t.Name}, nil
+		case "int":
+			return &schema{Type: "integer"}, nil
+		case "float32", "float64":
+			return &schema{Type: "number"}, nil
+		}
+		typeSpec, ok := g.types[t.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown type %s", t.Name)
+		}
+		return g.schemaOf(typeSpec.Type)
+	case *ast.StructType:
+		return g.schemaOfStruct(t)
+	}
+	return nil, fmt.Errorf("unsupported type %T", expr)
+}
+
+// schemaOfStruct returns the schema of the struct, the properties are named
+// after the JSON tags of the fields.
+func (g *generator) schemaOfStruct(t *ast.StructType) (*schema, error) {
+	s := &schema{Type: "object", Properties: make(map[string]*schema)}
+	for _, field := range t.Fields.List {
+		name := jsonName(field)
+		if name == "" {
+			continue
+		}
+		property, err := g.schemaOf(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", name, err)
+		}
+		description, markers := parseComments(field.Doc)
+		property.Description = description
+		if err := applyMarkers(property, markers); err != nil {
+			return nil, fmt.Errorf("field %s: %v", name, err)
+		}
+		s.Properties[name] = property
+	}
+	return s, nil
+}
+
+// jsonName returns the JSON name of the field, it is empty if the field is
+// skipped or inlined.
+func jsonName(field *ast.Field) string {
+	if field.Tag == nil || len(field.Names) == 0 {
+		return ""
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	name := strings.Split(reflect.StructTag(tag).Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// parseComments splits the comments into the description and the markers
+// with the validation prefix.
+func parseComments(doc *ast.CommentGroup) (string, []string) {
+	if doc == nil {
+		return "", nil
+	}
+	var lines, markers []string
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, validationMarker):
+			markers = append(markers, strings.TrimPrefix(line, validationMarker))
+		case strings.HasPrefix(line, "+"), line == "":
+		default:
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, " "), markers
+}
+
+// applyMarkers sets the validations given by the markers.
+func applyMarkers(s *schema, markers []string) error {
+	for _, marker := range markers {
+		parts := strings.SplitN(marker, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid marker %q", marker)
+		}
+		key, value := parts[0], parts[1]
+		switch key {
+		case "Minimum", "Maximum":
+			i, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid marker %q: %v", marker, err)
+			}
+			if key == "Minimum" {
+				s.Minimum = &i
+			} else {
+				s.Maximum = &i
+			}
+		case "Pattern":
+			s.Pattern = value
+		case "Enum":
+			s.Enum = strings.Split(value, ";")
+		default:
+			return fmt.Errorf("unknown marker %q", marker)
+		}
+	}
+	return nil
+}
+
+// typeMarkers returns the markers with the prefix in the comments of the
+// type.
+func (g *generator) typeMarkers(typeName, prefix string) []string {
+	doc := g.docs[typeName]
+	if doc == nil {
+		return nil
+	}
+	var markers []string
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, prefix) {
+			markers = append(markers, strings.TrimPrefix(line, prefix))
+		}
+	}
+	return markers
+}
+
+// parseKeyValues parses the marker in the form of k1=v1,k2=v2.
+func parseKeyValues(marker string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, kv := range strings.Split(marker, ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid marker %q", marker)
+		}
+		values[parts[0]] = parts[1]
+	}
+	return values, nil
+}
+
+// printColumns returns the printer columns in the comments of the type.
+func (g *generator) printColumns(typeName string) ([]printColumn, error) {
+	var columns []printColumn
+	for _, marker := range g.typeMarkers(typeName, printColumnMarker) {
+		values, err := parseKeyValues(marker)
+		if err != nil {
+			return nil, err
+		}
+		column := printColumn{}
+		for key, value := range values {
+			switch key {
+			case "name":
+				column.Name = value
+			case "type":
+				column.Type = value
+			case "JSONPath":
+				column.JSONPath = value
+			default:
+				return nil, fmt.Errorf("unknown key %s of printer column %q", key, marker)
+			}
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// subresources returns the subresources in the comments of the type.
+func (g *generator) subresources(typeName string) (*crdSubresources, error) {
+	subresources := &crdSubresources{}
+	if len(g.typeMarkers(typeName, statusMarker)) != 0 {
+		subresources.Status = &struct{}{}
+	}
+	for _, marker := range g.typeMarkers(typeName, scaleMarker) {
+		values, err := parseKeyValues(marker)
+		if err != nil {
+			return nil, err
+		}
+		scale := &scaleSubresource{}
+		for key, value := range values {
+			switch key {
+			case "specpath":
+				scale.SpecReplicasPath = value
+			case "statuspath":
+				scale.StatusReplicasPath = value
+			case "selectorpath":
+				scale.LabelSelectorPath = value
+			default:
+				return nil, fmt.Errorf("unknown key %s of scale subresource %q", key, marker)
+			}
+		}
+		subresources.Scale = scale
+	}
+	if subresources.Status == nil && subresources.Scale == nil {
+		return nil, nil
+	}
+	return subresources, nil
+}
+
+// generate returns the manifest of the CustomResourceDefinition.
+func (g *generator) generate() ([]byte, error) {
+	spec, err := g.schemaOf(ast.NewIdent("ClusterSpec"))
+	if err != nil {
+		return nil, err
+	}
+	columns, err := g.printColumns(api.ResourceKind)
+	if err != nil {
+		return nil, err
+	}
+	subresources, err := g.subresources(api.ResourceKind)
+	if err != nil {
+		return nil, err
+	}
+
+	kind := api.ResourceKind
+	plural := strings.ToLower(kind) + "s"
+	definition := crd{
+		APIVersion: "apiextensions.k8s.io/v1beta1",
+		Kind:       "CustomResourceDefinition",
+		Metadata:   map[string]string{"name": fmt.Sprintf("%s.%s", plural, api.GroupName)},
+		Spec: crdSpec{
+			Group:   api.GroupName,
+			Version: api.SchemeGroupVersion.Version,
+			// The first version is the storage version, the objects of the
+			// other versions are converted by the webhook.
+			Versions: []crdVersion{
+				{Name: api.SchemeGroupVersion.Version, Served: true, Storage: true},
+				{Name: v1alpha1.SchemeGroupVersion.Version, Served: true, Storage: false},
+			},
+			Names: crdNames{
+				Kind:     kind,
+				Singular: strings.ToLower(kind),
+				Plural:   plural,
+			},
+			Scope: "Namespaced",
+			Conversion: &crdConversion{
+				Strategy: "Webhook",
+				WebhookClientConfig: &webhookClientConfig{
+					Service: serviceReference{
+						Namespace: webhookNamespace,
+						Name:      webhookName,
+						Path:      webhook.ConvertPath,
+					},
+				},
+			},
+			Subresources: subresources,
+			Validation: &crdValidation{
+				OpenAPIV3Schema: &schema{
+					Type:       "object",
+					Properties: map[string]*schema{"spec": spec},
+				},
+			},
+			AdditionalPrinterColumns: columns,
+		},
+	}
+
+	data, err := yaml.Marshal(definition)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	buf.Write(data)
+	return buf.Bytes(), nil
+}
+
+func init() {
+	flag.StringVar(&inputDir, "input-dir", "pkg/apis/tidb/v1beta1", "The directory of the API package.")
+	flag.StringVar(&output, "output", "artifacts/crd/crd.yml", "The file to write the CustomResourceDefinition to.")
+	flag.StringVar(&webhookNamespace, "webhook-namespace", "default", "The namespace of the service of the conversion webhook.")
+	flag.StringVar(&webhookName, "webhook-name", "kubetidb-webhook", "The name of the service of the conversion webhook.")
+}
+
+func main() {
+	flag.Parse()
+
+	g, err := newGenerator(inputDir)
+	if err != nil {
+		glog.Fatalf("Error parsing %s: %s", inputDir, err.Error())
+	}
+	data, err := g.generate()
+	if err != nil {
+		glog.Fatalf("Error generating the CustomResourceDefinition: %s", err.Error())
+	}
+	if err := ioutil.WriteFile(output, data, 0644); err != nil {
+		glog.Fatalf("Error writing %s: %s", output, err.Error())
+	}
+}
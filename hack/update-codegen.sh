@@ -30,4 +30,15 @@ echo "CODEGEN_PKG: ${CODEGEN_PKG}"
 
 ${CODEGEN_PKG}/generate-groups.sh "all" \
   github.com/gaocegege/kubetidb/pkg github.com/gaocegege/kubetidb/pkg/apis \
-  tidb:v1alpha1
+  tidb:v1alpha1,v1beta1
+
+# generate-groups.sh does not run defaulter-gen yet.
+${GOPATH}/bin/defaulter-gen \
+  --input-dirs github.com/gaocegege/kubetidb/pkg/apis/tidb/v1alpha1,github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1 \
+  -O zz_generated.defaults
+
+# The validation schema and the printer columns of the CRD are derived from
+# the API types.
+go run ${ROOT}/hack/crd-gen/main.go \
+  --input-dir ${ROOT}/pkg/apis/tidb/v1beta1 \
+  --output ${ROOT}/artifacts/crd/crd.yml
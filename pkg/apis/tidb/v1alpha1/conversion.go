@@ -0,0 +1,330 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/conversion"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+// The conversions below convert v1alpha1 from and to v1beta1, which is the
+// storage version. The status of v1beta1 has typed timestamps and instances.
+// v1alpha1 is frozen, the fields added to v1beta1 since then are kept in an
+// annotation of the v1alpha1 object, so that they survive an update through
+// v1alpha1.
+
+// annotationV1beta1Fields is the annotation key of the fields of v1beta1
+// which v1alpha1 does not have, in JSON. It is only set on the v1alpha1
+// objects.
+const annotationV1beta1Fields = "kubetidb.gaocegege.com/v1beta1-fields"
+
+// v1beta1Fields is the fields of v1beta1 which v1alpha1 does not have.
+type v1beta1Fields struct {
+	Paused bool                 `json:"paused,omitempty"`
+	PD     *v1beta1PDFields     `json:"pd,omitempty"`
+	TiKV   *v1beta1TiKVFields   `json:"tikv,omitempty"`
+	TiDB   *v1beta1TiDBFields   `json:"tidb,omitempty"`
+	Status *v1beta1StatusFields `json:"status,omitempty"`
+}
+
+type v1beta1PDFields struct {
+	FailoverGracePeriod *metav1.Duration   `json:"failoverGracePeriod,omitempty"`
+	Config              *v1beta1.PDConfig  `json:"config,omitempty"`
+	StorageSize         *resource.Quantity `json:"storageSize,omitempty"`
+	StorageClassName    *string            `json:"storageClassName,omitempty"`
+}
+
+type v1beta1TiKVFields struct {
+	RetainPVCsOnScaleIn bool                `json:"retainPVCsOnScaleIn,omitempty"`
+	FailoverGracePeriod *metav1.Duration    `json:"failoverGracePeriod,omitempty"`
+	Config              *v1beta1.TiKVConfig `json:"config,omitempty"`
+}
+
+type v1beta1TiDBFields struct {
+	MaxFailoverCount    *int32              `json:"maxFailoverCount,omitempty"`
+	FailoverGracePeriod *metav1.Duration    `json:"failoverGracePeriod,omitempty"`
+	Config              *v1beta1.TiDBConfig `json:"config,omitempty"`
+}
+
+type v1beta1StatusFields struct {
+	PD   v1beta1.PDStatus   `json:"pd,omitempty"`
+	TiKV v1beta1.TiKVStatus `json:"tikv,omitempty"`
+	TiDB v1beta1.TiDBStatus `json:"tidb,omitempty"`
+}
+
+func addConversionFuncs(scheme *runtime.Scheme) error {
+	return scheme.AddConversionFuncs(
+		Convert_v1alpha1_TiDB_To_v1beta1_TiDB,
+		Convert_v1beta1_TiDB_To_v1alpha1_TiDB,
+		Convert_v1alpha1_TiDBList_To_v1beta1_TiDBList,
+		Convert_v1beta1_TiDBList_To_v1alpha1_TiDBList,
+	)
+}
+
+func Convert_v1alpha1_TiDB_To_v1beta1_TiDB(in *TiDB, out *v1beta1.TiDB, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	Convert_v1alpha1_ClusterSpec_To_v1beta1_ClusterSpec(&in.Spec, &out.Spec)
+	Convert_v1alpha1_ClusterStatus_To_v1beta1_ClusterStatus(&in.Status, &out.Status)
+
+	value, ok := in.Annotations[annotationV1beta1Fields]
+	if !ok {
+		return nil
+	}
+	var fields v1beta1Fields
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return fmt.Errorf("invalid annotation %s: %v", annotationV1beta1Fields, err)
+	}
+	restoreV1beta1Fields(&fields, out)
+	out.Annotations = make(map[string]string, len(in.Annotations)-1)
+	for k, v := range in.Annotations {
+		if k != annotationV1beta1Fields {
+			out.Annotations[k] = v
+		}
+	}
+	return nil
+}
+
+func Convert_v1beta1_TiDB_To_v1alpha1_TiDB(in *v1beta1.TiDB, out *TiDB, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	Convert_v1beta1_ClusterSpec_To_v1alpha1_ClusterSpec(&in.Spec, &out.Spec)
+	Convert_v1beta1_ClusterStatus_To_v1alpha1_ClusterStatus(&in.Status, &out.Status)
+
+	fields := getV1beta1Fields(in)
+	if fields == nil {
+		return nil
+	}
+	value, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	out.Annotations = make(map[string]string, len(in.Annotations)+1)
+	for k, v := range in.Annotations {
+		out.Annotations[k] = v
+	}
+	out.Annotations[annotationV1beta1Fields] = string(value)
+	return nil
+}
+
+func Convert_v1alpha1_TiDBList_To_v1beta1_TiDBList(in *TiDBList, out *v1beta1.TiDBList, s conversion.Scope) error {
+	out.ListMeta = in.ListMeta
+	out.Items = make([]v1beta1.TiDB, len(in.Items))
+	for i := range in.Items {
+		if err := Convert_v1alpha1_TiDB_To_v1beta1_TiDB(&in.Items[i], &out.Items[i], s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Convert_v1beta1_TiDBList_To_v1alpha1_TiDBList(in *v1beta1.TiDBList, out *TiDBList, s conversion.Scope) error {
+	out.ListMeta = in.ListMeta
+	out.Items = make([]TiDB, len(in.Items))
+	for i := range in.Items {
+		if err := Convert_v1beta1_TiDB_To_v1alpha1_TiDB(&in.Items[i], &out.Items[i], s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Convert_v1alpha1_ClusterSpec_To_v1beta1_ClusterSpec(in *ClusterSpec, out *v1beta1.ClusterSpec) {
+	out.PDSpec = v1beta1.PDSpec{
+		Replicas: in.PDSpec.Replicas,
+		Template: in.PDSpec.Template,
+	}
+	out.TiKVSpec = v1beta1.TiKVSpec{
+		Replicas:             in.TiKVSpec.Replicas,
+		Template:             in.TiKVSpec.Template,
+		StorageSize:          in.TiKVSpec.StorageSize,
+		StorageClassName:     in.TiKVSpec.StorageClassName,
+		VolumeClaimTemplates: in.TiKVSpec.VolumeClaimTemplates,
+	}
+	out.TiDBSpec = v1beta1.TiDBSpec{
+		Replicas: in.TiDBSpec.Replicas,
+		Template: in.TiDBSpec.Template,
+		Service: v1beta1.TiDBServiceSpec{
+			Type:                  in.TiDBSpec.Service.Type,
+			Annotations:           in.TiDBSpec.Service.Annotations,
+			ExternalTrafficPolicy: in.TiDBSpec.Service.ExternalTrafficPolicy,
+		},
+	}
+	out.RetainPVCs = in.RetainPVCs
+}
+
+func Convert_v1beta1_ClusterSpec_To_v1alpha1_ClusterSpec(in *v1beta1.ClusterSpec, out *ClusterSpec) {
+	out.PDSpec = PDSpec{
+		Replicas: in.PDSpec.Replicas,
+		Template: in.PDSpec.Template,
+	}
+	out.TiKVSpec = TiKVSpec{
+		Replicas:             in.TiKVSpec.Replicas,
+		Template:             in.TiKVSpec.Template,
+		StorageSize:          in.TiKVSpec.StorageSize,
+		StorageClassName:     in.TiKVSpec.StorageClassName,
+		VolumeClaimTemplates: in.TiKVSpec.VolumeClaimTemplates,
+	}
+	out.TiDBSpec = TiDBSpec{
+		Replicas: in.TiDBSpec.Replicas,
+		Template: in.TiDBSpec.Template,
+		Service: TiDBServiceSpec{
+			Type:                  in.TiDBSpec.Service.Type,
+			Annotations:           in.TiDBSpec.Service.Annotations,
+			ExternalTrafficPolicy: in.TiDBSpec.Service.ExternalTrafficPolicy,
+		},
+	}
+	out.RetainPVCs = in.RetainPVCs
+}
+
+// getV1beta1Fields returns the fields of the TiDB which v1alpha1 does not
+// have, it is nil if none of them is set.
+func getV1beta1Fields(in *v1beta1.TiDB) *v1beta1Fields {
+	fields := &v1beta1Fields{Paused: in.Spec.Paused}
+	if pd := in.Spec.PDSpec; pd.FailoverGracePeriod != nil || pd.Config != nil || pd.StorageSize != nil || pd.StorageClassName != nil {
+		fields.PD = &v1beta1PDFields{
+			FailoverGracePeriod: pd.FailoverGracePeriod,
+			Config:              pd.Config,
+			StorageSize:         pd.StorageSize,
+			StorageClassName:    pd.StorageClassName,
+		}
+	}
+	if tikv := in.Spec.TiKVSpec; tikv.RetainPVCsOnScaleIn || tikv.FailoverGracePeriod != nil || tikv.Config != nil {
+		fields.TiKV = &v1beta1TiKVFields{
+			RetainPVCsOnScaleIn: tikv.RetainPVCsOnScaleIn,
+			FailoverGracePeriod: tikv.FailoverGracePeriod,
+			Config:              tikv.Config,
+		}
+	}
+	if tidb := in.Spec.TiDBSpec; tidb.MaxFailoverCount != nil || tidb.FailoverGracePeriod != nil || tidb.Config != nil {
+		fields.TiDB = &v1beta1TiDBFields{
+			MaxFailoverCount:    tidb.MaxFailoverCount,
+			FailoverGracePeriod: tidb.FailoverGracePeriod,
+			Config:              tidb.Config,
+		}
+	}
+	status := &v1beta1StatusFields{
+		PD:   in.Status.PD,
+		TiKV: in.Status.TiKV,
+		TiDB: in.Status.TiDB,
+	}
+	if !reflect.DeepEqual(*status, v1beta1StatusFields{}) {
+		fields.Status = status
+	}
+	if reflect.DeepEqual(*fields, v1beta1Fields{}) {
+		return nil
+	}
+	return fields
+}
+
+// restoreV1beta1Fields sets the fields which v1alpha1 does not have.
+func restoreV1beta1Fields(fields *v1beta1Fields, out *v1beta1.TiDB) {
+	out.Spec.Paused = fields.Paused
+	if pd := fields.PD; pd != nil {
+		out.Spec.PDSpec.FailoverGracePeriod = pd.FailoverGracePeriod
+		out.Spec.PDSpec.Config = pd.Config
+		out.Spec.PDSpec.StorageSize = pd.StorageSize
+		out.Spec.PDSpec.StorageClassName = pd.StorageClassName
+	}
+	if tikv := fields.TiKV; tikv != nil {
+		out.Spec.TiKVSpec.RetainPVCsOnScaleIn = tikv.RetainPVCsOnScaleIn
+		out.Spec.TiKVSpec.FailoverGracePeriod = tikv.FailoverGracePeriod
+		out.Spec.TiKVSpec.Config = tikv.Config
+	}
+	if tidb := fields.TiDB; tidb != nil {
+		out.Spec.TiDBSpec.MaxFailoverCount = tidb.MaxFailoverCount
+		out.Spec.TiDBSpec.FailoverGracePeriod = tidb.FailoverGracePeriod
+		out.Spec.TiDBSpec.Config = tidb.Config
+	}
+	if status := fields.Status; status != nil {
+		out.Status.PD = status.PD
+		out.Status.TiKV = status.TiKV
+		out.Status.TiDB = status.TiDB
+	}
+}
+
+// Convert_v1alpha1_ClusterStatus_To_v1beta1_ClusterStatus parses the
+// timestamps of the conditions, and sorts the instances by name.
+func Convert_v1alpha1_ClusterStatus_To_v1beta1_ClusterStatus(in *ClusterStatus, out *v1beta1.ClusterStatus) {
+	out.Phase = v1beta1.ClusterPhase(in.Phase)
+	out.StartTime = in.StartTime
+	out.CompletionTime = in.CompletionTime
+
+	out.Conditions = nil
+	for _, condition := range in.Conditions {
+		if condition == nil {
+			continue
+		}
+		out.Conditions = append(out.Conditions, v1beta1.ClusterCondition{
+			Type:               v1beta1.ClusterConditionType(condition.Type),
+			Status:             condition.Status,
+			LastUpdateTime:     parseTime(condition.LastUpdateTime),
+			LastTransitionTime: parseTime(condition.LastTransitionTime),
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+		})
+	}
+
+	out.Instances = nil
+	for name, state := range in.InstanceStatus {
+		out.Instances = append(out.Instances, v1beta1.InstanceStatus{
+			Name:  name,
+			State: v1beta1.InstanceState(state),
+		})
+	}
+	sort.Slice(out.Instances, func(i, j int) bool {
+		return out.Instances[i].Name < out.Instances[j].Name
+	})
+}
+
+// Convert_v1beta1_ClusterStatus_To_v1alpha1_ClusterStatus formats the
+// timestamps of the conditions in RFC3339.
+func Convert_v1beta1_ClusterStatus_To_v1alpha1_ClusterStatus(in *v1beta1.ClusterStatus, out *ClusterStatus) {
+	out.Phase = ClusterPhase(in.Phase)
+	out.StartTime = in.StartTime
+	out.CompletionTime = in.CompletionTime
+
+	out.Conditions = nil
+	for _, condition := range in.Conditions {
+		out.Conditions = append(out.Conditions, &ClusterCondition{
+			Type:               ClusterConditionType(condition.Type),
+			Status:             condition.Status,
+			LastUpdateTime:     formatTime(condition.LastUpdateTime),
+			LastTransitionTime: formatTime(condition.LastTransitionTime),
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+		})
+	}
+
+	out.InstanceStatus = nil
+	if in.Instances != nil {
+		out.InstanceStatus = make(InstanceStatus, len(in.Instances))
+	}
+	for _, instance := range in.Instances {
+		out.InstanceStatus[instance.Name] = string(instance.State)
+	}
+}
+
+// parseTime parses the RFC3339 timestamp, the zero time is returned if it is
+// empty or malformed.
+func parseTime(value string) metav1.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return metav1.Time{}
+	}
+	return metav1.NewTime(t)
+}
+
+// formatTime formats the timestamp in RFC3339, it is empty for the zero time.
+func formatTime(t metav1.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
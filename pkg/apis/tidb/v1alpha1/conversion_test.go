@@ -0,0 +1,100 @@
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+func TestConvertV1beta1FieldsRoundTrip(t *testing.T) {
+	replicas := int32(3)
+	storageClassName := "ssd"
+	maxFailoverCount := int32(1)
+	in := &v1beta1.TiDB{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Annotations: map[string]string{"foo": "bar"},
+		},
+		Spec: v1beta1.ClusterSpec{
+			PDSpec: v1beta1.PDSpec{
+				Replicas:            &replicas,
+				FailoverGracePeriod: &metav1.Duration{Duration: time.Minute},
+				StorageClassName:    &storageClassName,
+				Config: &v1beta1.PDConfig{
+					Log: &v1beta1.PDLogConfig{Level: "warn"},
+				},
+			},
+			TiKVSpec: v1beta1.TiKVSpec{
+				Replicas:            &replicas,
+				RetainPVCsOnScaleIn: true,
+			},
+			TiDBSpec: v1beta1.TiDBSpec{
+				MaxFailoverCount: &maxFailoverCount,
+				Config:           &v1beta1.TiDBConfig{Raw: "[log]\nlevel = \"info\"\n"},
+			},
+			Paused: true,
+		},
+		Status: v1beta1.ClusterStatus{
+			Phase: v1beta1.ClusterPhaseRunning,
+			PD: v1beta1.PDStatus{
+				FailureMembers: []v1beta1.FailureMember{{PodName: "foo-pd-1", MemberID: 2}},
+				ScalingIn:      &v1beta1.PDScaleInStatus{PodName: "foo-pd-3"},
+			},
+			TiKV: v1beta1.TiKVStatus{
+				ScalingIn: &v1beta1.ScaleInStatus{PodName: "foo-tikv-3", StoreID: 4, State: "Offline"},
+			},
+			TiDB: v1beta1.TiDBStatus{Replicas: 2},
+		},
+	}
+
+	alpha := &TiDB{}
+	if err := Convert_v1beta1_TiDB_To_v1alpha1_TiDB(in, alpha, nil); err != nil {
+		t.Fatalf("Failed to convert to v1alpha1: %v", err)
+	}
+	if _, ok := alpha.Annotations[annotationV1beta1Fields]; !ok {
+		t.Fatalf("Expected annotation %s on the v1alpha1 object", annotationV1beta1Fields)
+	}
+	if _, ok := in.Annotations[annotationV1beta1Fields]; ok {
+		t.Errorf("Expected the v1beta1 object not to be changed")
+	}
+
+	out := &v1beta1.TiDB{}
+	if err := Convert_v1alpha1_TiDB_To_v1beta1_TiDB(alpha, out, nil); err != nil {
+		t.Fatalf("Failed to convert to v1beta1: %v", err)
+	}
+	if !reflect.DeepEqual(in.Spec, out.Spec) {
+		t.Errorf("Expected spec %+v, got %+v", in.Spec, out.Spec)
+	}
+	if !reflect.DeepEqual(in.Status.PD, out.Status.PD) || !reflect.DeepEqual(in.Status.TiKV, out.Status.TiKV) ||
+		!reflect.DeepEqual(in.Status.TiDB, out.Status.TiDB) {
+		t.Errorf("Expected status %+v, got %+v", in.Status, out.Status)
+	}
+	if !reflect.DeepEqual(in.Annotations, out.Annotations) {
+		t.Errorf("Expected annotations %v, got %v", in.Annotations, out.Annotations)
+	}
+}
+
+func TestConvertWithoutV1beta1Fields(t *testing.T) {
+	in := &v1beta1.TiDB{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	alpha := &TiDB{}
+	if err := Convert_v1beta1_TiDB_To_v1alpha1_TiDB(in, alpha, nil); err != nil {
+		t.Fatalf("Failed to convert to v1alpha1: %v", err)
+	}
+	if alpha.Annotations != nil {
+		t.Errorf("Expected no annotations, got %v", alpha.Annotations)
+	}
+}
+
+func TestConvertInvalidV1beta1Fields(t *testing.T) {
+	in := &TiDB{ObjectMeta: metav1.ObjectMeta{
+		Name:        "foo",
+		Annotations: map[string]string{annotationV1beta1Fields: "{"},
+	}}
+	if err := Convert_v1alpha1_TiDB_To_v1beta1_TiDB(in, &v1beta1.TiDB{}, nil); err == nil {
+		t.Errorf("Expected an error for the malformed annotation")
+	}
+}
@@ -0,0 +1,81 @@
+package v1alpha1
+
+import (
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// DefaultPDImage is the image of PD if the template is not given.
+	DefaultPDImage = "pingcap/pd:latest"
+	// DefaultTiKVImage is the image of TiKV if the template is not given.
+	DefaultTiKVImage = "pingcap/tikv:latest"
+	// DefaultTiDBImage is the image of TiDB if the template is not given.
+	DefaultTiDBImage = "pingcap/tidb:latest"
+	// DefaultTiKVStorageSize is the size of the persistent volume of each
+	// TiKV store if it is not given.
+	DefaultTiKVStorageSize = "10Gi"
+	// DefaultTiKVReplicas is the number of TiKV stores if it is not given,
+	// every region has 3 replicas by default.
+	DefaultTiKVReplicas = 3
+)
+
+func addDefaultingFuncs(scheme *runtime.Scheme) error {
+	return RegisterDefaults(scheme)
+}
+
+// SetDefaults_PDSpec sets the defaults of PD.
+func SetDefaults_PDSpec(obj *PDSpec) {
+	if obj.Replicas == nil {
+		obj.Replicas = newInt32(1)
+	}
+	if obj.Template == nil {
+		obj.Template = newTemplate("pd", DefaultPDImage)
+	}
+}
+
+// SetDefaults_TiKVSpec sets the defaults of TiKV.
+func SetDefaults_TiKVSpec(obj *TiKVSpec) {
+	if obj.Replicas == nil {
+		obj.Replicas = newInt32(DefaultTiKVReplicas)
+	}
+	if obj.Template == nil {
+		obj.Template = newTemplate("tikv", DefaultTiKVImage)
+	}
+	if obj.StorageSize == nil {
+		size := resource.MustParse(DefaultTiKVStorageSize)
+		obj.StorageSize = &size
+	}
+}
+
+// SetDefaults_TiDBSpec sets the defaults of TiDB.
+func SetDefaults_TiDBSpec(obj *TiDBSpec) {
+	if obj.Replicas == nil {
+		obj.Replicas = newInt32(1)
+	}
+	if obj.Template == nil {
+		obj.Template = newTemplate("tidb", DefaultTiDBImage)
+	}
+	if obj.Service.Type == "" {
+		obj.Service.Type = v1.ServiceTypeClusterIP
+	}
+}
+
+func newInt32(i int32) *int32 {
+	return &i
+}
+
+// newTemplate returns the template with a single container.
+func newTemplate(name, image string) *v1.PodTemplateSpec {
+	return &v1.PodTemplateSpec{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name:  name,
+					Image: image,
+				},
+			},
+		},
+	}
+}
@@ -1,3 +1,4 @@
 // +k8s:deepcopy-gen=package,register
+// +k8s:defaulter-gen=TypeMeta
 // +groupName=kubetidb.gaocegege.com
 package v1alpha1
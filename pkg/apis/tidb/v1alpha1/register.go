@@ -9,8 +9,8 @@ import (
 const (
 	// GroupName is the group name use in this package.
 	GroupName = "kubetidb.gaocegege.com"
-	// TFJobResourceKind is the kind name.
-	TFJobResourceKind = "TiDB"
+	// ResourceKind is the kind name of the TiDB clusters.
+	ResourceKind = "TiDB"
 	// GroupVersion is the version.
 	GroupVersion = "v1alpha1"
 )
@@ -34,6 +34,7 @@ func init() {
 	// generated functions takes place in the generated files. The separation
 	// makes the code compile even when the generated files are missing.
 	SchemeBuilder.Register(addKnownTypes)
+	localSchemeBuilder.Register(addDefaultingFuncs, addConversionFuncs)
 }
 
 // Adds the list of known types to api.Scheme.
@@ -1,14 +1,22 @@
 package v1alpha1
 
 import (
+	"encoding/json"
+
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // +genclient
-// +genclient:noStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// TiDB is a TiDB cluster, which consists of PD, TiKV and TiDB.
+// +kubetidb:printcolumn:name=Phase,type=string,JSONPath=.status.phase
+// +kubetidb:printcolumn:name=PD,type=integer,JSONPath=.spec.pd.replicas
+// +kubetidb:printcolumn:name=TiKV,type=integer,JSONPath=.spec.tikv.replicas
+// +kubetidb:printcolumn:name=TiDB,type=integer,JSONPath=.spec.tidb.replicas
+// +kubetidb:printcolumn:name=Age,type=date,JSONPath=.metadata.creationTimestamp
 type TiDB struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -20,27 +28,63 @@ type ClusterSpec struct {
 	PDSpec   PDSpec   `json:"pd"`
 	TiKVSpec TiKVSpec `json:"tikv"`
 	TiDBSpec TiDBSpec `json:"tidb"`
+
+	// Optional. Keep the persistent volume claims of the cluster when it is
+	// deleted. Default false.
+	RetainPVCs bool `json:"retainPVCs,omitempty"`
 }
 
 type PDSpec struct {
 	// Optional. The number of desired replicas. Default 1.
+	// +kubetidb:validation:Minimum=1
 	Replicas *int32 `json:"replicas,omitempty"`
 	// Template describes the data a pod should have when created from a template
 	Template *v1.PodTemplateSpec `json:"template,omitempty"`
 }
 
 type TiKVSpec struct {
-	// Optional. The number of desired replicas. Default 1.
+	// Optional. The number of desired replicas. Default 3. At least 3
+	// stores are required when the cluster is created or scaled, so that
+	// PD keeps 3 replicas of every region. The clusters created with fewer
+	// stores are kept until they are scaled.
+	// +kubetidb:validation:Minimum=1
 	Replicas *int32 `json:"replicas,omitempty"`
 	// Template describes the data a pod should have when created from a template
 	Template *v1.PodTemplateSpec `json:"template,omitempty"`
+	// Optional. The size of the persistent volume of each store. Default 10Gi.
+	StorageSize *resource.Quantity `json:"storageSize,omitempty"`
+	// Optional. The name of the StorageClass of the persistent volumes, the
+	// default StorageClass of the cluster is used if it is not given.
+	StorageClassName *string `json:"storageClassName,omitempty"`
+	// Optional. Additional claims of each store, they could be mounted by
+	// the containers in the template.
+	VolumeClaimTemplates []v1.PersistentVolumeClaim `json:"volumeClaimTemplates,omitempty"`
 }
 
 type TiDBSpec struct {
 	// Optional. The number of desired replicas. Default 1.
+	// +kubetidb:validation:Minimum=1
 	Replicas *int32 `json:"replicas,omitempty"`
 	// Template describes the data a pod should have when created from a template
 	Template *v1.PodTemplateSpec `json:"template,omitempty"`
+	// Optional. Service describes the service exposing the TiDB servers.
+	Service TiDBServiceSpec `json:"service,omitempty"`
+}
+
+// TiDBServiceSpec describes the service exposing the MySQL port and the
+// status port of the TiDB servers.
+type TiDBServiceSpec struct {
+	// Optional. The type of the service, one of ClusterIP, NodePort and
+	// LoadBalancer. Default ClusterIP.
+	// +kubetidb:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	Type v1.ServiceType `json:"type,omitempty"`
+	// Optional. The annotations of the service, e.g. the ones configuring
+	// the load balancer of the cloud provider.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Optional. The external traffic policy of the service, one of Cluster
+	// and Local. It only takes effect for NodePort and LoadBalancer services.
+	// +kubetidb:validation:Enum=Cluster;Local
+	ExternalTrafficPolicy v1.ServiceExternalTrafficPolicyType `json:"externalTrafficPolicy,omitempty"`
 }
 
 // ClusterStatus define the most recently observed status of the cluster.
@@ -66,17 +110,56 @@ type ClusterStatus struct {
 	InstanceStatus InstanceStatus `json:"instanceStatus"`
 }
 
+// ClusterPhase is the lifecycle phase of a TiDB cluster.
 type ClusterPhase string
 
 const (
-	TFJobNone      ClusterPhase = ""
-	TFJobUnknown                = "Unknown"
-	TFJobPending                = "Pending"
-	TFJobRunning                = "Running"
-	TFJobSucceeded              = "Succeeded"
-	TFJobFailed                 = "Failed"
+	// ClusterPhaseNone means the cluster is not acknowledged by the controller yet.
+	ClusterPhaseNone ClusterPhase = ""
+	// ClusterPhaseCreating means the resources of the cluster are being created.
+	ClusterPhaseCreating ClusterPhase = "Creating"
+	// ClusterPhaseBootstrapping means all the instances are created, and the
+	// cluster is waiting for them to be ready for the first time.
+	ClusterPhaseBootstrapping ClusterPhase = "Bootstrapping"
+	// ClusterPhaseRunning means all the instances are ready.
+	ClusterPhaseRunning ClusterPhase = "Running"
+	// ClusterPhaseScaling means the instances are being added or removed.
+	ClusterPhaseScaling ClusterPhase = "Scaling"
+	// ClusterPhaseUpgrading means the instances are being replaced with a
+	// new template.
+	ClusterPhaseUpgrading ClusterPhase = "Upgrading"
+	// ClusterPhaseDegraded means the cluster has been running, but some of
+	// the instances are not ready now.
+	ClusterPhaseDegraded ClusterPhase = "Degraded"
+	// ClusterPhaseDeleting means the resources of the cluster are being torn down.
+	ClusterPhaseDeleting ClusterPhase = "Deleting"
+	// ClusterPhaseFailed means some of the instances failed.
+	ClusterPhaseFailed ClusterPhase = "Failed"
 )
 
+// legacyClusterPhases maps the phases written by older versions of the
+// controller to the current ones.
+var legacyClusterPhases = map[string]ClusterPhase{
+	"Unknown":   ClusterPhaseNone,
+	"Pending":   ClusterPhaseCreating,
+	"Succeeded": ClusterPhaseRunning,
+}
+
+// UnmarshalJSON decodes the phase, the legacy phases of stored objects are
+// converted to the current ones.
+func (p *ClusterPhase) UnmarshalJSON(data []byte) error {
+	var phase string
+	if err := json.Unmarshal(data, &phase); err != nil {
+		return err
+	}
+	if legacy, ok := legacyClusterPhases[phase]; ok {
+		*p = legacy
+		return nil
+	}
+	*p = ClusterPhase(phase)
+	return nil
+}
+
 type InstanceStatus map[string]string
 
 // ClusterCondition represents one current condition of a TiDB cluster.
@@ -22,6 +22,7 @@ package v1alpha1
 
 import (
 	core_v1 "k8s.io/api/core/v1"
+	resource "k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
@@ -211,6 +212,29 @@ func (in *TiDBList) DeepCopyObject() runtime.Object {
 	}
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiDBServiceSpec) DeepCopyInto(out *TiDBServiceSpec) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiDBServiceSpec.
+func (in *TiDBServiceSpec) DeepCopy() *TiDBServiceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TiDBServiceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TiDBSpec) DeepCopyInto(out *TiDBSpec) {
 	*out = *in
@@ -232,6 +256,7 @@ func (in *TiDBSpec) DeepCopyInto(out *TiDBSpec) {
 			(*in).DeepCopyInto(*out)
 		}
 	}
+	in.Service.DeepCopyInto(&out.Service)
 	return
 }
 
@@ -266,6 +291,31 @@ func (in *TiKVSpec) DeepCopyInto(out *TiKVSpec) {
 			(*in).DeepCopyInto(*out)
 		}
 	}
+	if in.StorageSize != nil {
+		in, out := &in.StorageSize, &out.StorageSize
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(resource.Quantity)
+			**out = (*in).DeepCopy()
+		}
+	}
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(string)
+			**out = **in
+		}
+	}
+	if in.VolumeClaimTemplates != nil {
+		in, out := &in.VolumeClaimTemplates, &out.VolumeClaimTemplates
+		*out = make([]core_v1.PersistentVolumeClaim, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -0,0 +1,115 @@
+package v1beta1
+
+import (
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// DefaultPDImage is the image of PD if the template is not given.
+	DefaultPDImage = "pingcap/pd:latest"
+	// DefaultTiKVImage is the image of TiKV if the template is not given.
+	DefaultTiKVImage = "pingcap/tikv:latest"
+	// DefaultTiDBImage is the image of TiDB if the template is not given.
+	DefaultTiDBImage = "pingcap/tidb:latest"
+	// DefaultPDStorageSize is the size of the persistent volume of each PD
+	// member if it is not given.
+	DefaultPDStorageSize = "1Gi"
+	// DefaultTiKVStorageSize is the size of the persistent volume of each
+	// TiKV store if it is not given.
+	DefaultTiKVStorageSize = "10Gi"
+	// DefaultTiKVReplicas is the number of TiKV stores if it is not given,
+	// every region has 3 replicas by default.
+	DefaultTiKVReplicas = 3
+	// DefaultPDFailoverGracePeriod is how long a PD member is unhealthy
+	// before it is replaced if it is not given.
+	DefaultPDFailoverGracePeriod = 5 * time.Minute
+	// DefaultTiKVFailoverGracePeriod is how long a TiKV store is unhealthy
+	// before it is replaced if it is not given.
+	DefaultTiKVFailoverGracePeriod = 5 * time.Minute
+	// DefaultTiDBMaxFailoverCount is the maximum number of the extra TiDB
+	// servers replacing the unhealthy ones if it is not given.
+	DefaultTiDBMaxFailoverCount = 3
+	// DefaultTiDBFailoverGracePeriod is how long a TiDB server is unhealthy
+	// before it is replaced if it is not given.
+	DefaultTiDBFailoverGracePeriod = 5 * time.Minute
+)
+
+func addDefaultingFuncs(scheme *runtime.Scheme) error {
+	return RegisterDefaults(scheme)
+}
+
+// SetDefaults_PDSpec sets the defaults of PD.
+func SetDefaults_PDSpec(obj *PDSpec) {
+	if obj.Replicas == nil {
+		obj.Replicas = newInt32(1)
+	}
+	if obj.Template == nil {
+		obj.Template = newTemplate("pd", DefaultPDImage)
+	}
+	if obj.StorageSize == nil {
+		size := resource.MustParse(DefaultPDStorageSize)
+		obj.StorageSize = &size
+	}
+	if obj.FailoverGracePeriod == nil {
+		obj.FailoverGracePeriod = &metav1.Duration{Duration: DefaultPDFailoverGracePeriod}
+	}
+}
+
+// SetDefaults_TiKVSpec sets the defaults of TiKV.
+func SetDefaults_TiKVSpec(obj *TiKVSpec) {
+	if obj.Replicas == nil {
+		obj.Replicas = newInt32(DefaultTiKVReplicas)
+	}
+	if obj.Template == nil {
+		obj.Template = newTemplate("tikv", DefaultTiKVImage)
+	}
+	if obj.StorageSize == nil {
+		size := resource.MustParse(DefaultTiKVStorageSize)
+		obj.StorageSize = &size
+	}
+	if obj.FailoverGracePeriod == nil {
+		obj.FailoverGracePeriod = &metav1.Duration{Duration: DefaultTiKVFailoverGracePeriod}
+	}
+}
+
+// SetDefaults_TiDBSpec sets the defaults of TiDB.
+func SetDefaults_TiDBSpec(obj *TiDBSpec) {
+	if obj.Replicas == nil {
+		obj.Replicas = newInt32(1)
+	}
+	if obj.Template == nil {
+		obj.Template = newTemplate("tidb", DefaultTiDBImage)
+	}
+	if obj.Service.Type == "" {
+		obj.Service.Type = v1.ServiceTypeClusterIP
+	}
+	if obj.MaxFailoverCount == nil {
+		obj.MaxFailoverCount = newInt32(DefaultTiDBMaxFailoverCount)
+	}
+	if obj.FailoverGracePeriod == nil {
+		obj.FailoverGracePeriod = &metav1.Duration{Duration: DefaultTiDBFailoverGracePeriod}
+	}
+}
+
+func newInt32(i int32) *int32 {
+	return &i
+}
+
+// newTemplate returns the template with a single container.
+func newTemplate(name, image string) *v1.PodTemplateSpec {
+	return &v1.PodTemplateSpec{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name:  name,
+					Image: image,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,538 @@
+package v1beta1
+
+import (
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// +genclient
+// +genclient:method=GetScale,verb=get,subresource=scale,result=k8s.io/api/autoscaling/v1.Scale
+// +genclient:method=UpdateScale,verb=update,subresource=scale,input=k8s.io/api/autoscaling/v1.Scale,result=k8s.io/api/autoscaling/v1.Scale
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// TiDB is a TiDB cluster, which consists of PD, TiKV and TiDB.
+// +kubetidb:printcolumn:name=Phase,type=string,JSONPath=.status.phase
+// +kubetidb:printcolumn:name=PD,type=integer,JSONPath=.spec.pd.replicas
+// +kubetidb:printcolumn:name=TiKV,type=integer,JSONPath=.spec.tikv.replicas
+// +kubetidb:printcolumn:name=TiDB,type=integer,JSONPath=.spec.tidb.replicas
+// +kubetidb:printcolumn:name=Age,type=date,JSONPath=.metadata.creationTimestamp
+// +kubetidb:subresource:status
+// +kubetidb:subresource:scale:specpath=.spec.tidb.replicas,statuspath=.status.tidb.replicas,selectorpath=.status.tidb.selector
+type TiDB struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ClusterSpec   `json:"spec"`
+	Status            ClusterStatus `json:"status"`
+}
+
+type ClusterSpec struct {
+	PDSpec   PDSpec   `json:"pd"`
+	TiKVSpec TiKVSpec `json:"tikv"`
+	TiDBSpec TiDBSpec `json:"tidb"`
+
+	// Optional. Keep the persistent volume claims of the cluster when it is
+	// deleted. Default false.
+	RetainPVCs bool `json:"retainPVCs,omitempty"`
+
+	// Optional. Pause the rolling upgrade of the cluster, no more pods are
+	// restarted until it is resumed. Default false.
+	Paused bool `json:"paused,omitempty"`
+}
+
+type PDSpec struct {
+	// Optional. The number of desired replicas. Default 1.
+	// +kubetidb:validation:Minimum=1
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Template describes the data a pod should have when created from a template
+	Template *v1.PodTemplateSpec `json:"template,omitempty"`
+	// Optional. How long a member is unhealthy before it is replaced, the
+	// member is unhealthy if its pod is not ready and PD reports it
+	// unhealthy, e.g. its data is lost. Default 5m.
+	FailoverGracePeriod *metav1.Duration `json:"failoverGracePeriod,omitempty"`
+	// Optional. The config of the PD members, it is rendered into the
+	// config file of PD.
+	Config *PDConfig `json:"config,omitempty"`
+	// Optional. The size of the persistent volume of each member. Default
+	// 1Gi.
+	StorageSize *resource.Quantity `json:"storageSize,omitempty"`
+	// Optional. The name of the StorageClass of the persistent volumes, the
+	// default StorageClass of the cluster is used if it is not given.
+	StorageClassName *string `json:"storageClassName,omitempty"`
+}
+
+type TiKVSpec struct {
+	// Optional. The number of desired replicas. Default 3. At least 3
+	// stores are required when the cluster is created or scaled, so that
+	// PD keeps 3 replicas of every region. The clusters created with fewer
+	// stores are kept until they are scaled.
+	// +kubetidb:validation:Minimum=1
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Template describes the data a pod should have when created from a template
+	Template *v1.PodTemplateSpec `json:"template,omitempty"`
+	// Optional. The size of the persistent volume of each store. Default 10Gi.
+	StorageSize *resource.Quantity `json:"storageSize,omitempty"`
+	// Optional. The name of the StorageClass of the persistent volumes, the
+	// default StorageClass of the cluster is used if it is not given.
+	StorageClassName *string `json:"storageClassName,omitempty"`
+	// Optional. Additional claims of each store, they could be mounted by
+	// the containers in the template.
+	VolumeClaimTemplates []v1.PersistentVolumeClaim `json:"volumeClaimTemplates,omitempty"`
+	// Optional. Keep the persistent volume claims of the stores removed by
+	// scaling in. A store scaled out again with the claims could not start
+	// since the data is tombstone in PD. Default false.
+	RetainPVCsOnScaleIn bool `json:"retainPVCsOnScaleIn,omitempty"`
+	// Optional. How long a store is unhealthy before a replacement store is
+	// added, the store is unhealthy if its pod is not ready or PD reports
+	// it down. Default 5m.
+	FailoverGracePeriod *metav1.Duration `json:"failoverGracePeriod,omitempty"`
+	// Optional. The config of the TiKV stores, it is rendered into the
+	// config file of TiKV.
+	Config *TiKVConfig `json:"config,omitempty"`
+}
+
+type TiDBSpec struct {
+	// Optional. The number of desired replicas. Default 1.
+	// +kubetidb:validation:Minimum=1
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Template describes the data a pod should have when created from a template
+	Template *v1.PodTemplateSpec `json:"template,omitempty"`
+	// Optional. Service describes the service exposing the TiDB servers.
+	Service TiDBServiceSpec `json:"service,omitempty"`
+	// Optional. The maximum number of the extra servers replacing the
+	// unhealthy ones, 0 disables the failover. Default 3.
+	// +kubetidb:validation:Minimum=0
+	MaxFailoverCount *int32 `json:"maxFailoverCount,omitempty"`
+	// Optional. How long a server is unhealthy before an extra server is
+	// added, the server is unhealthy if its pod is not ready, i.e. the
+	// status port does not serve. Default 5m.
+	FailoverGracePeriod *metav1.Duration `json:"failoverGracePeriod,omitempty"`
+	// Optional. The config of the TiDB servers, it is rendered into the
+	// config file of TiDB.
+	Config *TiDBConfig `json:"config,omitempty"`
+}
+
+// TiDBServiceSpec describes the service exposing the MySQL port and the
+// status port of the TiDB servers.
+type TiDBServiceSpec struct {
+	// Optional. The type of the service, one of ClusterIP, NodePort and
+	// LoadBalancer. Default ClusterIP.
+	// +kubetidb:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	Type v1.ServiceType `json:"type,omitempty"`
+	// Optional. The annotations of the service, e.g. the ones configuring
+	// the load balancer of the cloud provider. The ones removed from here
+	// are removed from the service, the ones added by others are kept.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Optional. The external traffic policy of the service, one of Cluster
+	// and Local. It only takes effect for NodePort and LoadBalancer services.
+	// +kubetidb:validation:Enum=Cluster;Local
+	ExternalTrafficPolicy v1.ServiceExternalTrafficPolicyType `json:"externalTrafficPolicy,omitempty"`
+}
+
+// The config types below are rendered into the TOML config files of the
+// components, the toml tags are the keys in the files. Only the common keys
+// are typed, the others could be given in the raw config. The new values of
+// the log levels of PD and TiDB, and of the schedule and the replication
+// config of PD are applied online, the other changes restart the pods.
+
+// PDConfig is the config of the PD members.
+type PDConfig struct {
+	// Optional. The config of the log.
+	Log *PDLogConfig `json:"log,omitempty" toml:"log"`
+	// Optional. The config of the scheduling.
+	Schedule *PDScheduleConfig `json:"schedule,omitempty" toml:"schedule"`
+	// Optional. The config of the replication of the regions.
+	Replication *PDReplicationConfig `json:"replication,omitempty" toml:"replication"`
+	// Optional. The raw TOML appended to the config file, for the keys
+	// which are not typed above. It must not set the typed keys.
+	Raw string `json:"raw,omitempty" toml:"-"`
+}
+
+// PDLogConfig is the config of the log of PD.
+type PDLogConfig struct {
+	// Optional. The log level, one of debug, info, warn, error and fatal.
+	// +kubetidb:validation:Enum=debug;info;warn;error;fatal
+	Level string `json:"level,omitempty" toml:"level,omitempty"`
+}
+
+// PDScheduleConfig is the config of the scheduling of PD.
+type PDScheduleConfig struct {
+	// Optional. How long a store is disconnected before it is down and its
+	// regions are replicated to the other stores, e.g. 30m.
+	MaxStoreDownTime string `json:"maxStoreDownTime,omitempty" toml:"max-store-down-time,omitempty"`
+	// Optional. The number of the leader scheduling tasks at a time.
+	// +kubetidb:validation:Minimum=0
+	LeaderScheduleLimit *int32 `json:"leaderScheduleLimit,omitempty" toml:"leader-schedule-limit,omitempty"`
+	// Optional. The number of the region scheduling tasks at a time.
+	// +kubetidb:validation:Minimum=0
+	RegionScheduleLimit *int32 `json:"regionScheduleLimit,omitempty" toml:"region-schedule-limit,omitempty"`
+	// Optional. The number of the replica scheduling tasks at a time.
+	// +kubetidb:validation:Minimum=0
+	ReplicaScheduleLimit *int32 `json:"replicaScheduleLimit,omitempty" toml:"replica-schedule-limit,omitempty"`
+}
+
+// PDReplicationConfig is the config of the replication of the regions.
+type PDReplicationConfig struct {
+	// Optional. The number of the replicas of each region.
+	// +kubetidb:validation:Minimum=1
+	MaxReplicas *int32 `json:"maxReplicas,omitempty" toml:"max-replicas,omitempty"`
+	// Optional. The labels of the stores to spread the replicas over, from
+	// the outermost one, e.g. zone and host.
+	LocationLabels []string `json:"locationLabels,omitempty" toml:"location-labels,omitempty"`
+}
+
+// TiKVConfig is the config of the TiKV stores.
+type TiKVConfig struct {
+	// Optional. The log level, one of trace, debug, info, warn, error and
+	// off.
+	// +kubetidb:validation:Enum=trace;debug;info;warn;error;off
+	LogLevel string `json:"logLevel,omitempty" toml:"log-level,omitempty"`
+	// Optional. The config of the server.
+	Server *TiKVServerConfig `json:"server,omitempty" toml:"server"`
+	// Optional. The config of the storage.
+	Storage *TiKVStorageConfig `json:"storage,omitempty" toml:"storage"`
+	// Optional. The config of the raft store.
+	RaftStore *TiKVRaftStoreConfig `json:"raftstore,omitempty" toml:"raftstore"`
+	// Optional. The raw TOML appended to the config file, for the keys
+	// which are not typed above. It must not set the typed keys.
+	Raw string `json:"raw,omitempty" toml:"-"`
+}
+
+// TiKVServerConfig is the config of the server of TiKV.
+type TiKVServerConfig struct {
+	// Optional. The number of the gRPC workers.
+	// +kubetidb:validation:Minimum=1
+	GRPCConcurrency *int32 `json:"grpcConcurrency,omitempty" toml:"grpc-concurrency,omitempty"`
+}
+
+// TiKVStorageConfig is the config of the storage of TiKV.
+type TiKVStorageConfig struct {
+	// Optional. The number of the workers of the scheduler.
+	// +kubetidb:validation:Minimum=1
+	SchedulerWorkerPoolSize *int32 `json:"schedulerWorkerPoolSize,omitempty" toml:"scheduler-worker-pool-size,omitempty"`
+}
+
+// TiKVRaftStoreConfig is the config of the raft store of TiKV.
+type TiKVRaftStoreConfig struct {
+	// Optional. Sync the raft log before it is applied, the data may be
+	// lost on a crash if it is disabled.
+	SyncLog *bool `json:"syncLog,omitempty" toml:"sync-log,omitempty"`
+	// Optional. The size of the data written to a region before it is
+	// checked to be split, e.g. 32MB.
+	RegionSplitCheckDiff string `json:"regionSplitCheckDiff,omitempty" toml:"region-split-check-diff,omitempty"`
+}
+
+// TiDBConfig is the config of the TiDB servers.
+type TiDBConfig struct {
+	// Optional. The number of the sessions which execute requests at a time.
+	// +kubetidb:validation:Minimum=1
+	TokenLimit *int32 `json:"tokenLimit,omitempty" toml:"token-limit,omitempty"`
+	// Optional. What to do when a query exceeds the memory quota, one of log
+	// and cancel.
+	// +kubetidb:validation:Enum=log;cancel
+	OOMAction string `json:"oomAction,omitempty" toml:"oom-action,omitempty"`
+	// Optional. The config of the log.
+	Log *TiDBLogConfig `json:"log,omitempty" toml:"log"`
+	// Optional. The config of the performance.
+	Performance *TiDBPerformanceConfig `json:"performance,omitempty" toml:"performance"`
+	// Optional. The config of the cache of the prepared plans.
+	PreparedPlanCache *TiDBPreparedPlanCacheConfig `json:"preparedPlanCache,omitempty" toml:"prepared-plan-cache"`
+	// Optional. The raw TOML appended to the config file, for the keys
+	// which are not typed above. It must not set the typed keys.
+	Raw string `json:"raw,omitempty" toml:"-"`
+}
+
+// TiDBLogConfig is the config of the log of TiDB.
+type TiDBLogConfig struct {
+	// Optional. The log level, one of debug, info, warn, error and fatal.
+	// +kubetidb:validation:Enum=debug;info;warn;error;fatal
+	Level string `json:"level,omitempty" toml:"level,omitempty"`
+	// Optional. The threshold of the slow queries in milliseconds.
+	// +kubetidb:validation:Minimum=0
+	SlowThreshold *int32 `json:"slowThreshold,omitempty" toml:"slow-threshold,omitempty"`
+}
+
+// TiDBPerformanceConfig is the config of the performance of TiDB.
+type TiDBPerformanceConfig struct {
+	// Optional. The number of the CPUs used, 0 is all of them.
+	// +kubetidb:validation:Minimum=0
+	MaxProcs *int32 `json:"maxProcs,omitempty" toml:"max-procs,omitempty"`
+	// Optional. How often the statistics are reloaded, e.g. 3s.
+	StatsLease string `json:"statsLease,omitempty" toml:"stats-lease,omitempty"`
+}
+
+// TiDBPreparedPlanCacheConfig is the config of the cache of the prepared
+// plans.
+type TiDBPreparedPlanCacheConfig struct {
+	// Optional. Cache the plans of the prepared statements.
+	Enabled *bool `json:"enabled,omitempty" toml:"enabled,omitempty"`
+	// Optional. The number of the cached plans.
+	// +kubetidb:validation:Minimum=1
+	Capacity *int32 `json:"capacity,omitempty" toml:"capacity,omitempty"`
+}
+
+// ClusterStatus define the most recently observed status of the cluster.
+type ClusterStatus struct {
+	Phase ClusterPhase `json:"phase"`
+
+	// Represents time when the cluster was acknowledged by the cluster controller.
+	// It is not guaranteed to be set in happens-before order across separate operations.
+	// It is represented in RFC3339 form and is in UTC.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// Represents time when the cluster was completed. It is not guaranteed to
+	// be set in happens-before order across separate operations.
+	// It is represented in RFC3339 form and is in UTC.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Represents the latest available observations of a cluster object's current state.
+	// +optional
+	Conditions []ClusterCondition `json:"conditions,omitempty"`
+
+	// Instances represents the status of all the instances in the cluster,
+	// sorted by name.
+	// +optional
+	Instances []InstanceStatus `json:"instances,omitempty"`
+
+	// PD is the status of the PD members.
+	// +optional
+	PD PDStatus `json:"pd,omitempty"`
+
+	// TiKV is the status of the TiKV stores.
+	// +optional
+	TiKV TiKVStatus `json:"tikv,omitempty"`
+
+	// TiDB is the status of the TiDB servers, it backs the scale
+	// subresource.
+	// +optional
+	TiDB TiDBStatus `json:"tidb,omitempty"`
+}
+
+// PDStatus is the status of the PD members.
+type PDStatus struct {
+	// FailureMembers is the unhealthy members in the order of the pods. A
+	// member unhealthy beyond the failover grace period is deleted from PD,
+	// and its pod is recreated with fresh storage to join PD again.
+	// +optional
+	FailureMembers []FailureMember `json:"failureMembers,omitempty"`
+	// ScalingIn is the member being removed by scaling in, the claim of its
+	// pod is deleted once the pod is gone.
+	// +optional
+	ScalingIn *PDScaleInStatus `json:"scalingIn,omitempty"`
+}
+
+// PDScaleInStatus is the progress of the removal of a PD member.
+type PDScaleInStatus struct {
+	// PodName is the name of the pod, it is the name of the member as well.
+	PodName string `json:"podName"`
+	// StartTime is the time the removal started.
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// FailureMember is an unhealthy PD member.
+type FailureMember struct {
+	// PodName is the name of the pod, it is the name of the member as well.
+	PodName string `json:"podName"`
+	// MemberID is the ID of the member in PD.
+	MemberID uint64 `json:"memberID"`
+	// Since is the time the member was observed unhealthy first.
+	Since metav1.Time `json:"since"`
+	// MemberDeleted is true if the member has been deleted from PD, the
+	// pod joins PD again as a new member.
+	// +optional
+	MemberDeleted bool `json:"memberDeleted,omitempty"`
+}
+
+// TiKVStatus is the status of the TiKV stores.
+type TiKVStatus struct {
+	// EvictingLeader is the store whose region leaders are evicted before
+	// its pod is restarted. It is recorded so that the restart is resumed
+	// after the controller restarts.
+	// +optional
+	EvictingLeader *EvictLeaderStatus `json:"evictingLeader,omitempty"`
+	// ScalingIn is the store being removed by scaling in, its pod is
+	// deleted once all the regions are migrated to the other stores.
+	// +optional
+	ScalingIn *ScaleInStatus `json:"scalingIn,omitempty"`
+	// FailureStores is the unhealthy stores in the order of the pods. A
+	// replacement store is added for every store unhealthy beyond the
+	// failover grace period, it is removed once the store recovers.
+	// +optional
+	FailureStores []FailureStore `json:"failureStores,omitempty"`
+}
+
+// EvictLeaderStatus is the status of the eviction of the region leaders
+// from a TiKV store.
+type EvictLeaderStatus struct {
+	// PodName is the name of the pod of the store.
+	PodName string `json:"podName"`
+	// PodUID is the UID of the pod to be restarted, the pod is restarted
+	// once it is replaced by one with another UID.
+	PodUID types.UID `json:"podUID"`
+	// StoreID is the ID of the store in PD.
+	StoreID uint64 `json:"storeID"`
+	// StartTime is the time the eviction started.
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// ScaleInStatus is the progress of the removal of a TiKV store.
+type ScaleInStatus struct {
+	// PodName is the name of the pod of the store.
+	PodName string `json:"podName"`
+	// StoreID is the ID of the store in PD, it is 0 if the pod never
+	// registered a store.
+	// +optional
+	StoreID uint64 `json:"storeID,omitempty"`
+	// State is the state of the store in PD, i.e. Offline or Tombstone.
+	State string `json:"state"`
+	// RegionCount is the number of the regions remaining in the store.
+	RegionCount int32 `json:"regionCount"`
+	// StartTime is the time the removal started.
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// FailureStore is an unhealthy TiKV store.
+type FailureStore struct {
+	// PodName is the name of the pod of the store.
+	PodName string `json:"podName"`
+	// StoreID is the ID of the store in PD, it is 0 if the pod has not
+	// registered a store.
+	// +optional
+	StoreID uint64 `json:"storeID,omitempty"`
+	// Reason is why the store is unhealthy, i.e. PodNotReady or StoreDown.
+	Reason string `json:"reason"`
+	// Since is the time the store was observed unhealthy first.
+	Since metav1.Time `json:"since"`
+	// Replaced is true if a replacement store has been added for it.
+	// +optional
+	Replaced bool `json:"replaced,omitempty"`
+}
+
+// TiDBStatus is the status of the TiDB servers.
+type TiDBStatus struct {
+	// Replicas is the number of the existing TiDB server pods.
+	Replicas int32 `json:"replicas"`
+	// Selector is the label selector of the TiDB server pods in string form,
+	// it is used by the HorizontalPodAutoscaler.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+	// FailureMembers is the unhealthy servers sorted by the pod names. An
+	// extra server is added for every server unhealthy beyond the failover
+	// grace period, up to the maximum failover count.
+	// +optional
+	FailureMembers []TiDBFailureMember `json:"failureMembers,omitempty"`
+}
+
+// TiDBFailureMember is an unhealthy TiDB server.
+type TiDBFailureMember struct {
+	// PodName is the name of the pod of the server.
+	PodName string `json:"podName"`
+	// Since is the time the server was observed unhealthy first.
+	Since metav1.Time `json:"since"`
+	// Replaced is true if an extra server has been added for it.
+	// +optional
+	Replaced bool `json:"replaced,omitempty"`
+}
+
+// ClusterPhase is the lifecycle phase of a TiDB cluster.
+type ClusterPhase string
+
+const (
+	// ClusterPhaseNone means the cluster is not acknowledged by the controller yet.
+	ClusterPhaseNone ClusterPhase = ""
+	// ClusterPhaseCreating means the resources of the cluster are being created.
+	ClusterPhaseCreating ClusterPhase = "Creating"
+	// ClusterPhaseBootstrapping means all the instances are created, and the
+	// cluster is waiting for them to be ready for the first time.
+	ClusterPhaseBootstrapping ClusterPhase = "Bootstrapping"
+	// ClusterPhaseRunning means all the instances are ready.
+	ClusterPhaseRunning ClusterPhase = "Running"
+	// ClusterPhaseScaling means the instances are being added or removed.
+	ClusterPhaseScaling ClusterPhase = "Scaling"
+	// ClusterPhaseUpgrading means the instances are being replaced with a
+	// new template.
+	ClusterPhaseUpgrading ClusterPhase = "Upgrading"
+	// ClusterPhaseDegraded means the cluster has been running, but some of
+	// the instances are not ready now.
+	ClusterPhaseDegraded ClusterPhase = "Degraded"
+	// ClusterPhaseDeleting means the resources of the cluster are being torn down.
+	ClusterPhaseDeleting ClusterPhase = "Deleting"
+	// ClusterPhaseFailed means some of the instances failed.
+	ClusterPhaseFailed ClusterPhase = "Failed"
+)
+
+// InstanceStatus is the status of an instance, i.e. a pod of the cluster.
+type InstanceStatus struct {
+	// Name of the pod.
+	Name string `json:"name"`
+	// State of the pod.
+	State InstanceState `json:"state"`
+}
+
+// InstanceState is the state of an instance.
+type InstanceState string
+
+const (
+	// InstanceStatePending means the pod is not running yet.
+	InstanceStatePending InstanceState = "Pending"
+	// InstanceStateNotReady means the pod is running but not ready.
+	InstanceStateNotReady InstanceState = "NotReady"
+	// InstanceStateRunning means the pod is running and ready.
+	InstanceStateRunning InstanceState = "Running"
+	// InstanceStateSucceeded means the pod exited successfully.
+	InstanceStateSucceeded InstanceState = "Succeeded"
+	// InstanceStateFailed means the pod failed.
+	InstanceStateFailed InstanceState = "Failed"
+	// InstanceStateUnknown means the state of the pod could not be obtained.
+	InstanceStateUnknown InstanceState = "Unknown"
+)
+
+// ClusterCondition represents one current condition of a TiDB cluster.
+// A condition might not show up if it is not happening.
+// For example, if a cluster is not upgrading, the Upgrading condition would not show up.
+// If a cluster is upgrading and encountered a problem that prevents the upgrade,
+// the Upgrading condition's status will would be False and communicate the problem back.
+type ClusterCondition struct {
+	// Type of cluster condition.
+	Type ClusterConditionType `json:"type"`
+	// Status of the condition, one of True, False, Unknown.
+	Status v1.ConditionStatus `json:"status"`
+	// The last time this condition was updated.
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+	// Last time the condition transitioned from one status to another.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// The reason for the condition's last transition.
+	Reason string `json:"reason,omitempty"`
+	// A human readable message indicating details about the transition.
+	Message string `json:"message,omitempty"`
+}
+
+// ClusterConditionType is the type of the conditions of a TiDB cluster.
+type ClusterConditionType string
+
+const (
+	// ClusterConditionAvailable means all the instances are ready.
+	ClusterConditionAvailable ClusterConditionType = "Available"
+	// ClusterConditionFailed means some of the instances failed.
+	ClusterConditionFailed ClusterConditionType = "Failed"
+	// ClusterConditionUpgrading means the pods are being upgraded to the
+	// new templates, the reason tells the component being upgraded.
+	ClusterConditionUpgrading ClusterConditionType = "Upgrading"
+	// ClusterConditionScaleInRefused means TiKV could not be scaled in to
+	// the desired replicas, the stores are fewer than the replicas of each
+	// region in PD.
+	ClusterConditionScaleInRefused ClusterConditionType = "ScaleInRefused"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// TiDBList is a list of Foo resources
+type TiDBList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []TiDB `json:"items"`
+}
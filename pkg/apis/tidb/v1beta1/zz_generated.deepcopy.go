@@ -0,0 +1,1009 @@
+// +build !ignore_autogenerated
+
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file was autogenerated by deepcopy-gen. Do not edit it manually!
+
+package v1beta1
+
+import (
+	core_v1 "k8s.io/api/core/v1"
+	resource "k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterCondition) DeepCopyInto(out *ClusterCondition) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterCondition.
+func (in *ClusterCondition) DeepCopy() *ClusterCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
+	*out = *in
+	in.PDSpec.DeepCopyInto(&out.PDSpec)
+	in.TiKVSpec.DeepCopyInto(&out.TiKVSpec)
+	in.TiDBSpec.DeepCopyInto(&out.TiDBSpec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSpec.
+func (in *ClusterSpec) DeepCopy() *ClusterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterStatus) DeepCopyInto(out *ClusterStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]ClusterCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Instances != nil {
+		in, out := &in.Instances, &out.Instances
+		*out = make([]InstanceStatus, len(*in))
+		copy(*out, *in)
+	}
+	in.PD.DeepCopyInto(&out.PD)
+	in.TiKV.DeepCopyInto(&out.TiKV)
+	in.TiDB.DeepCopyInto(&out.TiDB)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterStatus.
+func (in *ClusterStatus) DeepCopy() *ClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EvictLeaderStatus) DeepCopyInto(out *EvictLeaderStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvictLeaderStatus.
+func (in *EvictLeaderStatus) DeepCopy() *EvictLeaderStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EvictLeaderStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FailureMember) DeepCopyInto(out *FailureMember) {
+	*out = *in
+	in.Since.DeepCopyInto(&out.Since)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailureMember.
+func (in *FailureMember) DeepCopy() *FailureMember {
+	if in == nil {
+		return nil
+	}
+	out := new(FailureMember)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FailureStore) DeepCopyInto(out *FailureStore) {
+	*out = *in
+	in.Since.DeepCopyInto(&out.Since)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailureStore.
+func (in *FailureStore) DeepCopy() *FailureStore {
+	if in == nil {
+		return nil
+	}
+	out := new(FailureStore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceStatus) DeepCopyInto(out *InstanceStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceStatus.
+func (in *InstanceStatus) DeepCopy() *InstanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PDConfig) DeepCopyInto(out *PDConfig) {
+	*out = *in
+	if in.Log != nil {
+		in, out := &in.Log, &out.Log
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(PDLogConfig)
+			**out = **in
+		}
+	}
+	if in.Schedule != nil {
+		in, out := &in.Schedule, &out.Schedule
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(PDScheduleConfig)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Replication != nil {
+		in, out := &in.Replication, &out.Replication
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(PDReplicationConfig)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PDConfig.
+func (in *PDConfig) DeepCopy() *PDConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(PDConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PDLogConfig) DeepCopyInto(out *PDLogConfig) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PDLogConfig.
+func (in *PDLogConfig) DeepCopy() *PDLogConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(PDLogConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PDReplicationConfig) DeepCopyInto(out *PDReplicationConfig) {
+	*out = *in
+	if in.MaxReplicas != nil {
+		in, out := &in.MaxReplicas, &out.MaxReplicas
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	if in.LocationLabels != nil {
+		in, out := &in.LocationLabels, &out.LocationLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PDReplicationConfig.
+func (in *PDReplicationConfig) DeepCopy() *PDReplicationConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(PDReplicationConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PDScaleInStatus) DeepCopyInto(out *PDScaleInStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PDScaleInStatus.
+func (in *PDScaleInStatus) DeepCopy() *PDScaleInStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PDScaleInStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PDScheduleConfig) DeepCopyInto(out *PDScheduleConfig) {
+	*out = *in
+	if in.LeaderScheduleLimit != nil {
+		in, out := &in.LeaderScheduleLimit, &out.LeaderScheduleLimit
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	if in.RegionScheduleLimit != nil {
+		in, out := &in.RegionScheduleLimit, &out.RegionScheduleLimit
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	if in.ReplicaScheduleLimit != nil {
+		in, out := &in.ReplicaScheduleLimit, &out.ReplicaScheduleLimit
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PDScheduleConfig.
+func (in *PDScheduleConfig) DeepCopy() *PDScheduleConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(PDScheduleConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PDSpec) DeepCopyInto(out *PDSpec) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.PodTemplateSpec)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.FailoverGracePeriod != nil {
+		in, out := &in.FailoverGracePeriod, &out.FailoverGracePeriod
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
+		}
+	}
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(PDConfig)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.StorageSize != nil {
+		in, out := &in.StorageSize, &out.StorageSize
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(resource.Quantity)
+			**out = (*in).DeepCopy()
+		}
+	}
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(string)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PDSpec.
+func (in *PDSpec) DeepCopy() *PDSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PDSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PDStatus) DeepCopyInto(out *PDStatus) {
+	*out = *in
+	if in.FailureMembers != nil {
+		in, out := &in.FailureMembers, &out.FailureMembers
+		*out = make([]FailureMember, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ScalingIn != nil {
+		in, out := &in.ScalingIn, &out.ScalingIn
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(PDScaleInStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PDStatus.
+func (in *PDStatus) DeepCopy() *PDStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PDStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleInStatus) DeepCopyInto(out *ScaleInStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleInStatus.
+func (in *ScaleInStatus) DeepCopy() *ScaleInStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleInStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiDB) DeepCopyInto(out *TiDB) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiDB.
+func (in *TiDB) DeepCopy() *TiDB {
+	if in == nil {
+		return nil
+	}
+	out := new(TiDB)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TiDB) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiDBConfig) DeepCopyInto(out *TiDBConfig) {
+	*out = *in
+	if in.TokenLimit != nil {
+		in, out := &in.TokenLimit, &out.TokenLimit
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	if in.Log != nil {
+		in, out := &in.Log, &out.Log
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(TiDBLogConfig)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Performance != nil {
+		in, out := &in.Performance, &out.Performance
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(TiDBPerformanceConfig)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.PreparedPlanCache != nil {
+		in, out := &in.PreparedPlanCache, &out.PreparedPlanCache
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(TiDBPreparedPlanCacheConfig)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiDBConfig.
+func (in *TiDBConfig) DeepCopy() *TiDBConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TiDBConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiDBFailureMember) DeepCopyInto(out *TiDBFailureMember) {
+	*out = *in
+	in.Since.DeepCopyInto(&out.Since)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiDBFailureMember.
+func (in *TiDBFailureMember) DeepCopy() *TiDBFailureMember {
+	if in == nil {
+		return nil
+	}
+	out := new(TiDBFailureMember)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiDBList) DeepCopyInto(out *TiDBList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TiDB, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiDBList.
+func (in *TiDBList) DeepCopy() *TiDBList {
+	if in == nil {
+		return nil
+	}
+	out := new(TiDBList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TiDBList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiDBLogConfig) DeepCopyInto(out *TiDBLogConfig) {
+	*out = *in
+	if in.SlowThreshold != nil {
+		in, out := &in.SlowThreshold, &out.SlowThreshold
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiDBLogConfig.
+func (in *TiDBLogConfig) DeepCopy() *TiDBLogConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TiDBLogConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiDBPerformanceConfig) DeepCopyInto(out *TiDBPerformanceConfig) {
+	*out = *in
+	if in.MaxProcs != nil {
+		in, out := &in.MaxProcs, &out.MaxProcs
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiDBPerformanceConfig.
+func (in *TiDBPerformanceConfig) DeepCopy() *TiDBPerformanceConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TiDBPerformanceConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiDBPreparedPlanCacheConfig) DeepCopyInto(out *TiDBPreparedPlanCacheConfig) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(bool)
+			**out = **in
+		}
+	}
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiDBPreparedPlanCacheConfig.
+func (in *TiDBPreparedPlanCacheConfig) DeepCopy() *TiDBPreparedPlanCacheConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TiDBPreparedPlanCacheConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiDBServiceSpec) DeepCopyInto(out *TiDBServiceSpec) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiDBServiceSpec.
+func (in *TiDBServiceSpec) DeepCopy() *TiDBServiceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TiDBServiceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiDBSpec) DeepCopyInto(out *TiDBSpec) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.PodTemplateSpec)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	in.Service.DeepCopyInto(&out.Service)
+	if in.MaxFailoverCount != nil {
+		in, out := &in.MaxFailoverCount, &out.MaxFailoverCount
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	if in.FailoverGracePeriod != nil {
+		in, out := &in.FailoverGracePeriod, &out.FailoverGracePeriod
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
+		}
+	}
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(TiDBConfig)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiDBSpec.
+func (in *TiDBSpec) DeepCopy() *TiDBSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TiDBSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiDBStatus) DeepCopyInto(out *TiDBStatus) {
+	*out = *in
+	if in.FailureMembers != nil {
+		in, out := &in.FailureMembers, &out.FailureMembers
+		*out = make([]TiDBFailureMember, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiDBStatus.
+func (in *TiDBStatus) DeepCopy() *TiDBStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TiDBStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVConfig) DeepCopyInto(out *TiKVConfig) {
+	*out = *in
+	if in.Server != nil {
+		in, out := &in.Server, &out.Server
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(TiKVServerConfig)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(TiKVStorageConfig)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.RaftStore != nil {
+		in, out := &in.RaftStore, &out.RaftStore
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(TiKVRaftStoreConfig)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVConfig.
+func (in *TiKVConfig) DeepCopy() *TiKVConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVRaftStoreConfig) DeepCopyInto(out *TiKVRaftStoreConfig) {
+	*out = *in
+	if in.SyncLog != nil {
+		in, out := &in.SyncLog, &out.SyncLog
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(bool)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVRaftStoreConfig.
+func (in *TiKVRaftStoreConfig) DeepCopy() *TiKVRaftStoreConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVRaftStoreConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVServerConfig) DeepCopyInto(out *TiKVServerConfig) {
+	*out = *in
+	if in.GRPCConcurrency != nil {
+		in, out := &in.GRPCConcurrency, &out.GRPCConcurrency
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVServerConfig.
+func (in *TiKVServerConfig) DeepCopy() *TiKVServerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVServerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVSpec) DeepCopyInto(out *TiKVSpec) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.PodTemplateSpec)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.StorageSize != nil {
+		in, out := &in.StorageSize, &out.StorageSize
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(resource.Quantity)
+			**out = (*in).DeepCopy()
+		}
+	}
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(string)
+			**out = **in
+		}
+	}
+	if in.VolumeClaimTemplates != nil {
+		in, out := &in.VolumeClaimTemplates, &out.VolumeClaimTemplates
+		*out = make([]core_v1.PersistentVolumeClaim, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FailoverGracePeriod != nil {
+		in, out := &in.FailoverGracePeriod, &out.FailoverGracePeriod
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
+		}
+	}
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(TiKVConfig)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVSpec.
+func (in *TiKVSpec) DeepCopy() *TiKVSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVStatus) DeepCopyInto(out *TiKVStatus) {
+	*out = *in
+	if in.EvictingLeader != nil {
+		in, out := &in.EvictingLeader, &out.EvictingLeader
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(EvictLeaderStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.ScalingIn != nil {
+		in, out := &in.ScalingIn, &out.ScalingIn
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(ScaleInStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.FailureStores != nil {
+		in, out := &in.FailureStores, &out.FailureStores
+		*out = make([]FailureStore, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVStatus.
+func (in *TiKVStatus) DeepCopy() *TiKVStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVStorageConfig) DeepCopyInto(out *TiKVStorageConfig) {
+	*out = *in
+	if in.SchedulerWorkerPoolSize != nil {
+		in, out := &in.SchedulerWorkerPoolSize, &out.SchedulerWorkerPoolSize
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVStorageConfig.
+func (in *TiKVStorageConfig) DeepCopy() *TiKVStorageConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVStorageConfig)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,163 @@
+package validation
+
+import (
+	"k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+const (
+	// minTiKVReplicas is the minimum number of TiKV stores, PD keeps 3
+	// replicas of every region by default.
+	minTiKVReplicas = 3
+)
+
+var supportedServiceTypes = map[v1.ServiceType]bool{
+	v1.ServiceTypeClusterIP:    true,
+	v1.ServiceTypeNodePort:     true,
+	v1.ServiceTypeLoadBalancer: true,
+}
+
+var supportedExternalTrafficPolicies = map[v1.ServiceExternalTrafficPolicyType]bool{
+	v1.ServiceExternalTrafficPolicyTypeCluster: true,
+	v1.ServiceExternalTrafficPolicyTypeLocal:   true,
+}
+
+// ValidateTiDB validates the TiDB cluster, the defaults are expected to be
+// set before.
+func ValidateTiDB(tidb *api.TiDB) field.ErrorList {
+	specPath := field.NewPath("spec")
+	allErrs := ValidateClusterSpec(&tidb.Spec, specPath)
+	return append(allErrs, validateTiKVReplicas(tidb.Spec.TiKVSpec.Replicas, specPath.Child("tikv", "replicas"))...)
+}
+
+// ValidateTiDBUpdate validates the update of the TiDB cluster. The changes
+// which could not be applied safely to a running cluster are rejected.
+func ValidateTiDBUpdate(tidb, oldTiDB *api.TiDB) field.ErrorList {
+	specPath := field.NewPath("spec")
+	allErrs := ValidateClusterSpec(&tidb.Spec, specPath)
+
+	// The clusters created with fewer TiKV stores before the minimum was
+	// enforced are kept until they are scaled.
+	if !apiequality.Semantic.DeepEqual(tidb.Spec.TiKVSpec.Replicas, oldTiDB.Spec.TiKVSpec.Replicas) {
+		allErrs = append(allErrs, validateTiKVReplicas(tidb.Spec.TiKVSpec.Replicas, specPath.Child("tikv", "replicas"))...)
+	}
+
+	// Removing more than a minority of the PD members at once loses the
+	// quorum of the raft group.
+	if tidb.Spec.PDSpec.Replicas != nil && oldTiDB.Spec.PDSpec.Replicas != nil {
+		replicas := *tidb.Spec.PDSpec.Replicas
+		quorum := *oldTiDB.Spec.PDSpec.Replicas/2 + 1
+		if replicas < quorum {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("pd", "replicas"), replicas,
+				"must not be less than the quorum of the current members"))
+		}
+	}
+
+	// The claims of the statefulsets could not be changed.
+	pdPath := specPath.Child("pd")
+	if !apiequality.Semantic.DeepEqual(tidb.Spec.PDSpec.StorageClassName, oldTiDB.Spec.PDSpec.StorageClassName) {
+		allErrs = append(allErrs, field.Forbidden(pdPath.Child("storageClassName"), "field is immutable"))
+	}
+	if !apiequality.Semantic.DeepEqual(tidb.Spec.PDSpec.StorageSize, oldTiDB.Spec.PDSpec.StorageSize) {
+		allErrs = append(allErrs, field.Forbidden(pdPath.Child("storageSize"), "field is immutable"))
+	}
+	tikvPath := specPath.Child("tikv")
+	if !apiequality.Semantic.DeepEqual(tidb.Spec.TiKVSpec.StorageClassName, oldTiDB.Spec.TiKVSpec.StorageClassName) {
+		allErrs = append(allErrs, field.Forbidden(tikvPath.Child("storageClassName"), "field is immutable"))
+	}
+	if !apiequality.Semantic.DeepEqual(tidb.Spec.TiKVSpec.StorageSize, oldTiDB.Spec.TiKVSpec.StorageSize) {
+		allErrs = append(allErrs, field.Forbidden(tikvPath.Child("storageSize"), "field is immutable"))
+	}
+	if !apiequality.Semantic.DeepEqual(tidb.Spec.TiKVSpec.VolumeClaimTemplates, oldTiDB.Spec.TiKVSpec.VolumeClaimTemplates) {
+		allErrs = append(allErrs, field.Forbidden(tikvPath.Child("volumeClaimTemplates"), "field is immutable"))
+	}
+	return allErrs
+}
+
+// ValidateClusterSpec validates the spec of the cluster.
+func ValidateClusterSpec(spec *api.ClusterSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	pdPath := fldPath.Child("pd")
+	if replicas := spec.PDSpec.Replicas; replicas != nil {
+		if *replicas < 1 {
+			allErrs = append(allErrs, field.Invalid(pdPath.Child("replicas"), *replicas, "must be greater than or equal to 1"))
+		} else if *replicas%2 == 0 {
+			allErrs = append(allErrs, field.Invalid(pdPath.Child("replicas"), *replicas, "must be odd"))
+		}
+	}
+	allErrs = append(allErrs, validateTemplate(spec.PDSpec.Template, "pd", pdPath.Child("template"))...)
+	if size := spec.PDSpec.StorageSize; size != nil && size.Sign() <= 0 {
+		allErrs = append(allErrs, field.Invalid(pdPath.Child("storageSize"), size.String(), "must be greater than 0"))
+	}
+
+	tikvPath := fldPath.Child("tikv")
+	if replicas := spec.TiKVSpec.Replicas; replicas != nil && *replicas < 1 {
+		allErrs = append(allErrs, field.Invalid(tikvPath.Child("replicas"), *replicas, "must be greater than or equal to 1"))
+	}
+	allErrs = append(allErrs, validateTemplate(spec.TiKVSpec.Template, "tikv", tikvPath.Child("template"))...)
+	if size := spec.TiKVSpec.StorageSize; size != nil && size.Sign() <= 0 {
+		allErrs = append(allErrs, field.Invalid(tikvPath.Child("storageSize"), size.String(), "must be greater than 0"))
+	}
+
+	tidbPath := fldPath.Child("tidb")
+	if replicas := spec.TiDBSpec.Replicas; replicas != nil && *replicas < 1 {
+		allErrs = append(allErrs, field.Invalid(tidbPath.Child("replicas"), *replicas, "must be greater than or equal to 1"))
+	}
+	allErrs = append(allErrs, validateTemplate(spec.TiDBSpec.Template, "tidb", tidbPath.Child("template"))...)
+	allErrs = append(allErrs, validateServiceSpec(&spec.TiDBSpec.Service, tidbPath.Child("service"))...)
+
+	return allErrs
+}
+
+// validateTiKVReplicas checks there are enough TiKV stores for the replicas
+// of the regions.
+func validateTiKVReplicas(replicas *int32, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if replicas != nil && *replicas < minTiKVReplicas {
+		allErrs = append(allErrs, field.Invalid(fldPath, *replicas, "must be greater than or equal to 3"))
+	}
+	return allErrs
+}
+
+// validateTemplate checks the template contains the container of the
+// component, the controller sets its arguments and ports.
+func validateTemplate(template *v1.PodTemplateSpec, container string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if template == nil {
+		return allErrs
+	}
+	for _, c := range template.Spec.Containers {
+		if c.Name == container {
+			if c.Image == "" {
+				allErrs = append(allErrs, field.Required(fldPath.Child("spec", "containers").Key(container).Child("image"), ""))
+			}
+			return allErrs
+		}
+	}
+	return append(allErrs, field.Required(fldPath.Child("spec", "containers"),
+		"must contain a container named "+container))
+}
+
+// validateServiceSpec validates the service exposing the TiDB servers.
+func validateServiceSpec(spec *api.TiDBServiceSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if spec.Type != "" && !supportedServiceTypes[spec.Type] {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("type"), spec.Type,
+			[]string{string(v1.ServiceTypeClusterIP), string(v1.ServiceTypeNodePort), string(v1.ServiceTypeLoadBalancer)}))
+	}
+	if spec.ExternalTrafficPolicy == "" {
+		return allErrs
+	}
+	if !supportedExternalTrafficPolicies[spec.ExternalTrafficPolicy] {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("externalTrafficPolicy"), spec.ExternalTrafficPolicy,
+			[]string{string(v1.ServiceExternalTrafficPolicyTypeCluster), string(v1.ServiceExternalTrafficPolicyTypeLocal)}))
+	} else if spec.Type != v1.ServiceTypeNodePort && spec.Type != v1.ServiceTypeLoadBalancer {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("externalTrafficPolicy"),
+			"may only be set when type is NodePort or LoadBalancer"))
+	}
+	return allErrs
+}
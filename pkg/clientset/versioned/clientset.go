@@ -18,6 +18,7 @@ package versioned
 
 import (
 	kubetidbv1alpha1 "github.com/gaocegege/kubetidb/pkg/clientset/versioned/typed/tidb/v1alpha1"
+	kubetidbv1beta1 "github.com/gaocegege/kubetidb/pkg/clientset/versioned/typed/tidb/v1beta1"
 	glog "github.com/golang/glog"
 	discovery "k8s.io/client-go/discovery"
 	rest "k8s.io/client-go/rest"
@@ -27,8 +28,9 @@ import (
 type Interface interface {
 	Discovery() discovery.DiscoveryInterface
 	KubetidbV1alpha1() kubetidbv1alpha1.KubetidbV1alpha1Interface
+	KubetidbV1beta1() kubetidbv1beta1.KubetidbV1beta1Interface
 	// Deprecated: please explicitly pick a version if possible.
-	Kubetidb() kubetidbv1alpha1.KubetidbV1alpha1Interface
+	Kubetidb() kubetidbv1beta1.KubetidbV1beta1Interface
 }
 
 // Clientset contains the clients for groups. Each group has exactly one
@@ -36,6 +38,7 @@ type Interface interface {
 type Clientset struct {
 	*discovery.DiscoveryClient
 	kubetidbV1alpha1 *kubetidbv1alpha1.KubetidbV1alpha1Client
+	kubetidbV1beta1  *kubetidbv1beta1.KubetidbV1beta1Client
 }
 
 // KubetidbV1alpha1 retrieves the KubetidbV1alpha1Client
@@ -43,10 +46,15 @@ func (c *Clientset) KubetidbV1alpha1() kubetidbv1alpha1.KubetidbV1alpha1Interfac
 	return c.kubetidbV1alpha1
 }
 
+// KubetidbV1beta1 retrieves the KubetidbV1beta1Client
+func (c *Clientset) KubetidbV1beta1() kubetidbv1beta1.KubetidbV1beta1Interface {
+	return c.kubetidbV1beta1
+}
+
 // Deprecated: Kubetidb retrieves the default version of KubetidbClient.
 // Please explicitly pick a version.
-func (c *Clientset) Kubetidb() kubetidbv1alpha1.KubetidbV1alpha1Interface {
-	return c.kubetidbV1alpha1
+func (c *Clientset) Kubetidb() kubetidbv1beta1.KubetidbV1beta1Interface {
+	return c.kubetidbV1beta1
 }
 
 // Discovery retrieves the DiscoveryClient
@@ -69,6 +77,10 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 	if err != nil {
 		return nil, err
 	}
+	cs.kubetidbV1beta1, err = kubetidbv1beta1.NewForConfig(&configShallowCopy)
+	if err != nil {
+		return nil, err
+	}
 
 	cs.DiscoveryClient, err = discovery.NewDiscoveryClientForConfig(&configShallowCopy)
 	if err != nil {
@@ -83,6 +95,7 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 func NewForConfigOrDie(c *rest.Config) *Clientset {
 	var cs Clientset
 	cs.kubetidbV1alpha1 = kubetidbv1alpha1.NewForConfigOrDie(c)
+	cs.kubetidbV1beta1 = kubetidbv1beta1.NewForConfigOrDie(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClientForConfigOrDie(c)
 	return &cs
@@ -92,6 +105,7 @@ func NewForConfigOrDie(c *rest.Config) *Clientset {
 func New(c rest.Interface) *Clientset {
 	var cs Clientset
 	cs.kubetidbV1alpha1 = kubetidbv1alpha1.New(c)
+	cs.kubetidbV1beta1 = kubetidbv1beta1.New(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClient(c)
 	return &cs
@@ -20,6 +20,8 @@ import (
 	clientset "github.com/gaocegege/kubetidb/pkg/clientset/versioned"
 	kubetidbv1alpha1 "github.com/gaocegege/kubetidb/pkg/clientset/versioned/typed/tidb/v1alpha1"
 	fakekubetidbv1alpha1 "github.com/gaocegege/kubetidb/pkg/clientset/versioned/typed/tidb/v1alpha1/fake"
+	kubetidbv1beta1 "github.com/gaocegege/kubetidb/pkg/clientset/versioned/typed/tidb/v1beta1"
+	fakekubetidbv1beta1 "github.com/gaocegege/kubetidb/pkg/clientset/versioned/typed/tidb/v1beta1/fake"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/discovery"
@@ -65,7 +67,12 @@ func (c *Clientset) KubetidbV1alpha1() kubetidbv1alpha1.KubetidbV1alpha1Interfac
 	return &fakekubetidbv1alpha1.FakeKubetidbV1alpha1{Fake: &c.Fake}
 }
 
-// Kubetidb retrieves the KubetidbV1alpha1Client
-func (c *Clientset) Kubetidb() kubetidbv1alpha1.KubetidbV1alpha1Interface {
-	return &fakekubetidbv1alpha1.FakeKubetidbV1alpha1{Fake: &c.Fake}
+// KubetidbV1beta1 retrieves the KubetidbV1beta1Client
+func (c *Clientset) KubetidbV1beta1() kubetidbv1beta1.KubetidbV1beta1Interface {
+	return &fakekubetidbv1beta1.FakeKubetidbV1beta1{Fake: &c.Fake}
+}
+
+// Kubetidb retrieves the KubetidbV1beta1Client
+func (c *Clientset) Kubetidb() kubetidbv1beta1.KubetidbV1beta1Interface {
+	return &fakekubetidbv1beta1.FakeKubetidbV1beta1{Fake: &c.Fake}
 }
@@ -18,6 +18,7 @@ package fake
 
 import (
 	kubetidbv1alpha1 "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1alpha1"
+	kubetidbv1beta1 "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
@@ -49,5 +50,6 @@ func init() {
 // correctly.
 func AddToScheme(scheme *runtime.Scheme) {
 	kubetidbv1alpha1.AddToScheme(scheme)
+	kubetidbv1beta1.AddToScheme(scheme)
 
 }
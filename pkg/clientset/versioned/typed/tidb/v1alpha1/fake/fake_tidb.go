@@ -98,6 +98,18 @@ func (c *FakeTiDBs) Update(tiDB *v1alpha1.TiDB) (result *v1alpha1.TiDB, err erro
 	return obj.(*v1alpha1.TiDB), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeTiDBs) UpdateStatus(tiDB *v1alpha1.TiDB) (*v1alpha1.TiDB, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(tidbsResource, "status", c.ns, tiDB), &v1alpha1.TiDB{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.TiDB), err
+}
+
 // Delete takes name of the tiDB and deletes it. Returns an error if one occurs.
 func (c *FakeTiDBs) Delete(name string, options *v1.DeleteOptions) error {
 	_, err := c.Fake.
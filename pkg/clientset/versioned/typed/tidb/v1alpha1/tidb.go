@@ -35,6 +35,7 @@ type TiDBsGetter interface {
 type TiDBInterface interface {
 	Create(*v1alpha1.TiDB) (*v1alpha1.TiDB, error)
 	Update(*v1alpha1.TiDB) (*v1alpha1.TiDB, error)
+	UpdateStatus(*v1alpha1.TiDB) (*v1alpha1.TiDB, error)
 	Delete(name string, options *v1.DeleteOptions) error
 	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
 	Get(name string, options v1.GetOptions) (*v1alpha1.TiDB, error)
@@ -118,6 +119,22 @@ func (c *tiDBs) Update(tiDB *v1alpha1.TiDB) (result *v1alpha1.TiDB, err error) {
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *tiDBs) UpdateStatus(tiDB *v1alpha1.TiDB) (result *v1alpha1.TiDB, err error) {
+	result = &v1alpha1.TiDB{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("tidbs").
+		Name(tiDB.Name).
+		SubResource("status").
+		Body(tiDB).
+		Do().
+		Into(result)
+	return
+}
+
 // Delete takes name of the tiDB and deletes it. Returns an error if one occurs.
 func (c *tiDBs) Delete(name string, options *v1.DeleteOptions) error {
 	return c.client.Delete().
@@ -0,0 +1,259 @@
+package controller
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path"
+	"strings"
+
+	"github.com/golang/glog"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	hashutil "k8s.io/kubernetes/pkg/util/hash"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+	"github.com/gaocegege/kubetidb/pkg/util/toml"
+)
+
+const (
+	// annotationConfigHash is the annotation key of the hash of the config
+	// in the pod template. The config map is mounted at the same path
+	// whatever the config is, so the template changes with the hash and the
+	// pods are restarted to load the new config. The values of the
+	// hot-reloadable keys are not hashed, they are applied online.
+	annotationConfigHash = "kubetidb.gaocegege.com/config-hash"
+
+	configVolume = "config"
+	configFile   = "config.toml"
+)
+
+// componentConfig is the rendered config of a component.
+type componentConfig struct {
+	// data is the config file.
+	data []byte
+	// hot is the values of the hot-reloadable keys.
+	hot map[string]interface{}
+	// hotHash is the hash of the values of the hot-reloadable keys.
+	hotHash string
+	// restartHash is the hash of the rest of the config, which takes
+	// effect after the pods restart.
+	restartHash string
+}
+
+// syncConfig renders the config of the component into the config map, and
+// mounts it into the pod template. The changed values of the hot-reloadable
+// keys are applied to the running component first, a change of the others
+// restarts the pods. Nothing is done if the config is not given, the config
+// map is kept if the config is removed later, it is deleted with the
+// cluster.
+func (c *Controller) syncConfig(tidb *api.TiDB, component componentType, template *v1.PodTemplateSpec) error {
+	config, err := renderConfig(tidb, component)
+	if err != nil {
+		return fmt.Errorf("invalid config of %s: %v", component, err)
+	}
+	if config == nil {
+		return nil
+	}
+
+	existing, err := c.configMapLister.ConfigMaps(tidb.Namespace).Get(genConfigMapName(tidb, component))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	// The pods started with a new config map load the values from the
+	// config file, there is nothing to apply.
+	if err == nil && metav1.IsControlledBy(existing, tidb) &&
+		existing.Annotations[annotationHotConfigHash] != config.hotHash && len(config.hot) != 0 {
+		if err := c.reloadConfig(tidb, component, config.hot); err != nil {
+			c.recorder.Eventf(tidb, v1.EventTypeWarning, ConfigReloadFailed, "Failed to reload the config of %s: %v", component, err)
+			return err
+		}
+		c.recorder.Eventf(tidb, v1.EventTypeNormal, ConfigReloaded, "Reloaded %s of %s online", strings.Join(sortedKeys(config.hot), ", "), component)
+	}
+
+	configMap := newConfigMap(tidb, component, string(config.data))
+	configMap.Annotations = map[string]string{annotationHotConfigHash: config.hotHash}
+	if err := c.syncConfigMap(tidb, configMap); err != nil {
+		return err
+	}
+	mountConfig(tidb, component, template, config.restartHash)
+	return nil
+}
+
+// syncConfigMap creates the config map if it does not exist, or updates it
+// if the rendered data changed.
+func (c *Controller) syncConfigMap(tidb *api.TiDB, desired *v1.ConfigMap) error {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return err
+	}
+	setManagedAnnotations(&desired.ObjectMeta)
+	setSpecHash(&desired.ObjectMeta, struct {
+		Annotations map[string]string
+		Data        map[string]string
+	}{desired.Annotations, desired.Data})
+
+	existing, err := c.configMapLister.ConfigMaps(tidb.Namespace).Get(desired.Name)
+	if errors.IsNotFound(err) {
+		c.raiseExpectations(key, 1, 0)
+		if _, err := c.kubeclientset.CoreV1().ConfigMaps(tidb.Namespace).Create(desired); err != nil {
+			// The config map informer won't observe the creation, so
+			// decrement the expected number of creates.
+			c.expectations.CreationObserved(key)
+			if !errors.IsAlreadyExists(err) {
+				return fmt.Errorf("failed to create config map %s: %v", desired.Name, err)
+			}
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(existing, tidb) {
+		return fmt.Errorf("config map %s already exists and is not owned by %s", existing.Name, key)
+	}
+
+	if existing.Annotations[annotationSpecHash] == desired.Annotations[annotationSpecHash] {
+		return nil
+	}
+	glog.V(4).Infof("Update config map %s/%s", existing.Namespace, existing.Name)
+	configMap := existing.DeepCopy()
+	configMap.Annotations = mergeAnnotations(configMap.Annotations, desired.Annotations)
+	configMap.Data = desired.Data
+	if _, err := c.kubeclientset.CoreV1().ConfigMaps(tidb.Namespace).Update(configMap); err != nil {
+		return fmt.Errorf("failed to update config map %s: %v", configMap.Name, err)
+	}
+	return nil
+}
+
+// deleteConfigMap deletes the config map owned by the TiDB.
+func (c *Controller) deleteConfigMap(tidb *api.TiDB, configMap *v1.ConfigMap) error {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return err
+	}
+
+	c.raiseExpectations(key, 0, 1)
+	if err := c.kubeclientset.CoreV1().ConfigMaps(tidb.Namespace).Delete(configMap.Name, &metav1.DeleteOptions{}); err != nil {
+		// The config map informer won't observe the deletion, so
+		// decrement the expected number of deletes.
+		c.expectations.DeletionObserved(key)
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete config map %s: %v", configMap.Name, err)
+		}
+	}
+	return nil
+}
+
+// newConfigMap returns the config map of the config file of the component.
+func newConfigMap(tidb *api.TiDB, component componentType, data string) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            genConfigMapName(tidb, component),
+			Namespace:       tidb.Namespace,
+			Labels:          genLabels(tidb, component),
+			OwnerReferences: []metav1.OwnerReference{*genOwnerReference(tidb)},
+		},
+		Data: map[string]string{configFile: data},
+	}
+}
+
+// mountConfig mounts the config map into the container of the component,
+// and points the component to the config file. A --config flag given by
+// users overrides the generated one.
+func mountConfig(tidb *api.TiDB, component componentType, template *v1.PodTemplateSpec, hash string) {
+	if template.Annotations == nil {
+		template.Annotations = make(map[string]string)
+	}
+	template.Annotations[annotationConfigHash] = hash
+
+	container := getContainer(&template.Spec, component)
+	container.Args = mergeArgs([]string{fmt.Sprintf("--config=%s", path.Join(genConfigDir(component), configFile))}, container.Args)
+	container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+		Name:      configVolume,
+		MountPath: genConfigDir(component),
+		ReadOnly:  true,
+	})
+	template.Spec.Volumes = append(template.Spec.Volumes, v1.Volume{
+		Name: configVolume,
+		VolumeSource: v1.VolumeSource{
+			ConfigMap: &v1.ConfigMapVolumeSource{
+				LocalObjectReference: v1.LocalObjectReference{Name: genConfigMapName(tidb, component)},
+			},
+		},
+	})
+}
+
+// renderConfig returns the config of the component, the typed config is
+// followed by the raw one in the config file. It is nil if the config is not
+// given.
+func renderConfig(tidb *api.TiDB, component componentType) (*componentConfig, error) {
+	var config interface{}
+	var raw string
+	switch component {
+	case componentPD:
+		if tidb.Spec.PDSpec.Config == nil {
+			return nil, nil
+		}
+		config, raw = tidb.Spec.PDSpec.Config, tidb.Spec.PDSpec.Config.Raw
+	case componentTiKV:
+		if tidb.Spec.TiKVSpec.Config == nil {
+			return nil, nil
+		}
+		config, raw = tidb.Spec.TiKVSpec.Config, tidb.Spec.TiKVSpec.Config.Raw
+	case componentTiDB:
+		if tidb.Spec.TiDBSpec.Config == nil {
+			return nil, nil
+		}
+		config, raw = tidb.Spec.TiDBSpec.Config, tidb.Spec.TiDBSpec.Config.Raw
+	default:
+		return nil, nil
+	}
+
+	data, err := toml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = toml.Merge(data, []byte(raw)); err != nil {
+		return nil, err
+	}
+	keys, err := toml.Flatten(config)
+	if err != nil {
+		return nil, err
+	}
+
+	hot, restart := classifyConfig(component, keys)
+	return &componentConfig{
+		data:    data,
+		hot:     hot,
+		hotHash: genConfigHash(hot),
+		// The hot-reloadable keys which are set are hashed without the
+		// values, the pods are restarted if one of them is unset since the
+		// default could not be applied online.
+		restartHash: genConfigHash(struct {
+			Keys    map[string]interface{}
+			HotKeys []string
+			Raw     string
+		}{restart, sortedKeys(hot), raw}),
+	}, nil
+}
+
+// genConfigHash returns the hash of the config.
+func genConfigHash(config interface{}) string {
+	hasher := fnv.New32a()
+	hashutil.DeepHashObject(hasher, config)
+	return fmt.Sprintf("%x", hasher.Sum32())
+}
+
+// genConfigMapName returns the name of the config map of the component,
+// e.g. foo-pd-config.
+func genConfigMapName(tidb *api.TiDB, component componentType) string {
+	return fmt.Sprintf("%s-config", genName(tidb, component))
+}
+
+// genConfigDir returns the directory the config map is mounted at, e.g.
+// /etc/pd.
+func genConfigDir(component componentType) string {
+	return path.Join("/etc", string(component))
+}
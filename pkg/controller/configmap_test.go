@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+func TestSyncRendersConfig(t *testing.T) {
+	tidb := newTiDB("foo")
+	tidb.Spec.TiKVSpec.Config = &api.TiKVConfig{LogLevel: "info"}
+	f := newFixture(t, tidb)
+	f.sync(tidb)
+	tidb = f.getTiDB(tidb)
+	created := f.created()
+
+	configMap, err := f.kubeclient.CoreV1().ConfigMaps(metav1.NamespaceDefault).Get("foo-tikv-config", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get the config map of TiKV: %v", err)
+	}
+	checkOwned(t, configMap, tidb)
+	if data := configMap.Data[configFile]; !strings.Contains(data, `log-level = "info"`) {
+		t.Errorf("Expected the log level in the config file, got %q", data)
+	}
+	if _, err := f.kubeclient.CoreV1().ConfigMaps(metav1.NamespaceDefault).Get("foo-pd-config", metav1.GetOptions{}); err == nil {
+		t.Errorf("Expected no config map of PD without its config")
+	}
+
+	template := f.getStatefulSet("foo-tikv").Spec.Template
+	hash := template.Annotations[annotationConfigHash]
+	if hash == "" {
+		t.Errorf("Expected the hash of the config in the pod template, got %v", template.Annotations)
+	}
+	mounted := false
+	for _, volume := range template.Spec.Volumes {
+		if volume.Name == configVolume && volume.ConfigMap != nil && volume.ConfigMap.Name == "foo-tikv-config" {
+			mounted = true
+		}
+	}
+	if !mounted {
+		t.Errorf("Expected the config map to be mounted, got %+v", template.Spec.Volumes)
+	}
+	container := getContainer(&template.Spec, componentTiKV)
+	if !containsString(container.Args, "--config=/etc/tikv/config.toml") {
+		t.Errorf("Expected TiKV to be pointed to the config file, got %v", container.Args)
+	}
+	f.close()
+
+	// TiKV could not change its config online, the pods are restarted with
+	// the new template.
+	tidb.Spec.TiKVSpec.Config.LogLevel = "debug"
+	f = newFixture(t, append([]runtime.Object{tidb}, created...)...)
+	defer f.close()
+	f.sync(tidb)
+	configMap, err = f.kubeclient.CoreV1().ConfigMaps(metav1.NamespaceDefault).Get("foo-tikv-config", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get the config map of TiKV: %v", err)
+	}
+	if data := configMap.Data[configFile]; !strings.Contains(data, `log-level = "debug"`) {
+		t.Errorf("Expected the new log level in the config file, got %q", data)
+	}
+	if got := f.getStatefulSet("foo-tikv").Spec.Template.Annotations[annotationConfigHash]; got == hash {
+		t.Errorf("Expected the hash of the config to change, got %s", got)
+	}
+}
+
+// containsString returns true if the string is one of the values.
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
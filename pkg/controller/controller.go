@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/golang/glog"
+	apps "k8s.io/api/apps/v1beta2"
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/util/runtime"
@@ -13,22 +14,29 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	appslisters "k8s.io/client-go/listers/apps/v1beta2"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/kubernetes/pkg/controller"
 
-	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1alpha1"
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
 	clientset "github.com/gaocegege/kubetidb/pkg/clientset/versioned"
 	tidbscheme "github.com/gaocegege/kubetidb/pkg/clientset/versioned/scheme"
 	informers "github.com/gaocegege/kubetidb/pkg/informers/externalversions"
-	listers "github.com/gaocegege/kubetidb/pkg/listers/tidb/v1alpha1"
+	listers "github.com/gaocegege/kubetidb/pkg/listers/tidb/v1beta1"
+	"github.com/gaocegege/kubetidb/pkg/pdapi"
+	"github.com/gaocegege/kubetidb/pkg/tidbapi"
 )
 
 const (
 	controllerName = "kubetidb"
 	// SuccessSynced is used as part of the Event 'reason' when a TiDB is synced
 	SuccessSynced = "Synced"
+	// FailedSync is used as part of the Event 'reason' when a TiDB fails to
+	// be synced
+	FailedSync = "FailedSync"
 
 	// MessageResourceSynced is the message used for an Event fired when a TiDB
 	// is synced successfully
@@ -44,6 +52,18 @@ type Controller struct {
 	tidbLister    listers.TiDBLister
 	tidbSynced    cache.InformerSynced
 
+	// Listers of the resources owned by TiDB clusters.
+	podLister         corelisters.PodLister
+	podSynced         cache.InformerSynced
+	serviceLister     corelisters.ServiceLister
+	serviceSynced     cache.InformerSynced
+	configMapLister   corelisters.ConfigMapLister
+	configMapSynced   cache.InformerSynced
+	statefulSetLister appslisters.StatefulSetLister
+	statefulSetSynced cache.InformerSynced
+	deploymentLister  appslisters.DeploymentLister
+	deploymentSynced  cache.InformerSynced
+
 	// workqueue is a rate limited work queue. This is used to queue work to be
 	// processed instead of performing it as soon as a change happens. This
 	// means we can ensure we only process a fixed amount of resources at a
@@ -56,21 +76,44 @@ type Controller struct {
 
 	// A TTLCache of tidb creates/deletes each rc expects to see
 	expectations controller.ControllerExpectationsInterface
+	// podExpectations tracks the pods deleted to be upgraded, so that only
+	// one pod is upgraded at a time.
+	podExpectations *controller.UIDTrackingControllerExpectations
+
+	// workers tracks the liveness of the workers for the health checks.
+	workers *workerTracker
+
+	// pdClientFor returns the client of the PD API at the URL, it is
+	// replaced to point the controller to a fake PD.
+	pdClientFor func(url string) pdapi.Client
+	// tidbClientFor returns the client of the API of the TiDB server at the
+	// URL, it is replaced to point the controller to a fake TiDB.
+	tidbClientFor func(url string) tidbapi.Client
 }
 
-// NewController returns a new tfJob controller.
+// NewController returns a new TiDB controller. The owned resources are
+// watched in the namespace only, which is empty for all namespaces, the
+// TiDB informer factory is expected to be scoped the same way.
 func NewController(
 	kubeclientset kubernetes.Interface,
 	tidbClientset clientset.Interface,
 	kubeInformerFactory kubeinformers.SharedInformerFactory,
-	tidbInformerFactory informers.SharedInformerFactory) *Controller {
-
-	// obtain references to shared index informers for the tfJob type
-	tidbInformer := tidbInformerFactory.Kubetidb().V1alpha1().TiDBs()
+	tidbInformerFactory informers.SharedInformerFactory,
+	namespace string,
+	rateLimiter workqueue.RateLimiter) *Controller {
+
+	// obtain references to shared index informers for the TiDB type
+	tidbInformer := tidbInformerFactory.Kubetidb().V1beta1().TiDBs()
+	// and the resources owned by TiDB clusters.
+	podInformer := ownedInformerFor(kubeInformerFactory, &v1.Pod{}, namespace)
+	serviceInformer := ownedInformerFor(kubeInformerFactory, &v1.Service{}, namespace)
+	configMapInformer := ownedInformerFor(kubeInformerFactory, &v1.ConfigMap{}, namespace)
+	statefulSetInformer := ownedInformerFor(kubeInformerFactory, &apps.StatefulSet{}, namespace)
+	deploymentInformer := ownedInformerFor(kubeInformerFactory, &apps.Deployment{}, namespace)
 
 	// Create event broadcaster
-	// Add tfJob-controller types to the default Kubernetes Scheme so Events can be
-	// logged for tfJob-controller types.
+	// Add the TiDB types to the default Kubernetes Scheme so Events can be
+	// logged for the TiDB types.
 	tidbscheme.AddToScheme(scheme.Scheme)
 	glog.V(4).Info("Creating event broadcaster")
 	eventBroadcaster := record.NewBroadcaster()
@@ -84,25 +127,54 @@ func NewController(
 		tidbLister:    tidbInformer.Lister(),
 		expectations:  controller.NewControllerExpectations(),
 		tidbSynced:    tidbInformer.Informer().HasSynced,
-		workqueue:     workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "tfJobs"),
+		workqueue:     workqueue.NewNamedRateLimitingQueue(rateLimiter, "tidb"),
 		recorder:      recorder,
+		workers:       newWorkerTracker(),
+
+		podExpectations: controller.NewUIDTrackingControllerExpectations(controller.NewControllerExpectations()),
+		pdClientFor:     newPDClient,
+		tidbClientFor:   newTiDBClient,
+
+		podLister:         corelisters.NewPodLister(podInformer.GetIndexer()),
+		podSynced:         podInformer.HasSynced,
+		serviceLister:     corelisters.NewServiceLister(serviceInformer.GetIndexer()),
+		serviceSynced:     serviceInformer.HasSynced,
+		configMapLister:   corelisters.NewConfigMapLister(configMapInformer.GetIndexer()),
+		configMapSynced:   configMapInformer.HasSynced,
+		statefulSetLister: appslisters.NewStatefulSetLister(statefulSetInformer.GetIndexer()),
+		statefulSetSynced: statefulSetInformer.HasSynced,
+		deploymentLister:  appslisters.NewDeploymentLister(deploymentInformer.GetIndexer()),
+		deploymentSynced:  deploymentInformer.HasSynced,
 	}
 
 	glog.Info("Setting up event handlers")
-	// Set up an event handler for when tfJob resources change
+	// Set up an event handler for when TiDB resources change
 	tidbInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    controller.addTiDB,
 		UpdateFunc: controller.updateTiDB,
 		DeleteFunc: controller.deleteTiDB,
 	})
 
+	// Set up an event handler for when the owned resources change, so the
+	// owner TiDB is synced and the expectations are observed.
+	ownedHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    controller.addOwned,
+		UpdateFunc: controller.updateOwned,
+		DeleteFunc: controller.deleteOwned,
+	}
+	podInformer.AddEventHandler(ownedHandler)
+	serviceInformer.AddEventHandler(ownedHandler)
+	configMapInformer.AddEventHandler(ownedHandler)
+	statefulSetInformer.AddEventHandler(ownedHandler)
+	deploymentInformer.AddEventHandler(ownedHandler)
+
 	controller.tidbLister = tidbInformer.Lister()
 
 	return controller
 }
 
 // syncHandler compares the actual state with the desired, and attempts to
-// converge the two. It then updates the Status block of the TFJob resource
+// converge the two. It then updates the Status block of the TiDB resource
 // with the current status of the resource.
 func (c *Controller) syncHandler(key string) error {
 	// Convert the namespace/name string into a distinct namespace and name
@@ -117,26 +189,89 @@ func (c *Controller) syncHandler(key string) error {
 
 	needsSync := c.expectations.SatisfiedExpectations(key)
 
-	// Get the TFJob resource with this namespace/name
+	// Get the TiDB resource with this namespace/name
 	TiDB, err := c.tidbLister.TiDBs(namespace).Get(name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			glog.V(4).Infof("Job has been deleted: %v", key)
 			c.expectations.DeleteExpectations(key)
+			c.podExpectations.DeleteExpectations(key)
 			return nil
 		}
 		return err
 	}
 
-	if needsSync {
-		c.syncCluster(TiDB)
+	if !needsSync {
+		return nil
+	}
+
+	// The cluster is being deleted, tear down the owned resources in order
+	// before the finalizer is removed.
+	if TiDB.DeletionTimestamp != nil {
+		return c.teardownCluster(TiDB)
+	}
+	if TiDB, err = c.ensureFinalizer(TiDB); err != nil {
+		return err
+	}
+
+	// The status is written during the sync, the TiDB in the cache must not
+	// be modified.
+	TiDB = TiDB.DeepCopy()
+	// The defaults are persisted by the mutating admission webhook, they are
+	// set in memory for the TiDBs created without it.
+	api.SetObjectDefaults_TiDB(TiDB)
+	if err := c.syncCluster(TiDB); err != nil {
+		return err
+	}
+	if err := c.updateStatus(TiDB); err != nil {
+		return err
 	}
+	c.recorder.Event(TiDB, v1.EventTypeNormal, SuccessSynced, MessageResourceSynced)
 
 	return nil
 }
 
-func (c *Controller) syncCluster(TiDB *api.TiDB) {
+// syncCluster reconciles PD, TiKV and TiDB of the cluster into the owned
+// workloads and services. PD comes first since TiKV and TiDB are pointed to
+// it, and the components are upgraded in the same order.
+func (c *Controller) syncCluster(TiDB *api.TiDB) error {
 	glog.V(4).Infof("Sync TiDB: %v", *TiDB)
+
+	pd, err := c.syncPD(TiDB)
+	if err != nil {
+		c.recorder.Eventf(TiDB, v1.EventTypeWarning, FailedSync, "Failed to sync PD: %v", err)
+		return err
+	}
+	if pd != nil {
+		if err := c.syncPDFailover(TiDB); err != nil {
+			c.recorder.Eventf(TiDB, v1.EventTypeWarning, FailedSync, "Failed to fail over PD: %v", err)
+			return err
+		}
+	}
+	tikv, err := c.syncTiKV(TiDB)
+	if err != nil {
+		c.recorder.Eventf(TiDB, v1.EventTypeWarning, FailedSync, "Failed to sync TiKV: %v", err)
+		return err
+	}
+	pdProgress, err := c.getUpgradeProgress(TiDB, componentPD, pd)
+	if err != nil {
+		return err
+	}
+	tikvProgress, err := c.getUpgradeProgress(TiDB, componentTiKV, tikv)
+	if err != nil {
+		return err
+	}
+
+	paused := TiDB.Spec.Paused || !pdProgress.done() || !tikvProgress.done()
+	if err := c.syncTiDB(TiDB, paused); err != nil {
+		c.recorder.Eventf(TiDB, v1.EventTypeWarning, FailedSync, "Failed to sync TiDB: %v", err)
+		return err
+	}
+	if err := c.upgradeCluster(TiDB, pdProgress, tikvProgress); err != nil {
+		c.recorder.Eventf(TiDB, v1.EventTypeWarning, FailedSync, "Failed to upgrade: %v", err)
+		return err
+	}
+	return nil
 }
 
 // Run will set up the event handlers for types we are interested in, as well
@@ -152,15 +287,16 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
 
 	// Wait for the caches to be synced before starting workers
 	glog.Info("Waiting for informer caches to sync")
-	if ok := cache.WaitForCacheSync(stopCh, c.tidbSynced); !ok {
+	if ok := cache.WaitForCacheSync(stopCh, c.tidbSynced, c.podSynced, c.serviceSynced, c.configMapSynced, c.statefulSetSynced, c.deploymentSynced); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
 
-	glog.Info("Starting workers")
-	// Launch two workers to process tfjob resources
+	glog.Infof("Starting %d workers", threadiness)
+	// Launch the workers to process TiDB resources
 	for i := 0; i < threadiness; i++ {
 		go wait.Until(c.runWorker, time.Second, stopCh)
 	}
+	c.workers.start()
 
 	glog.Info("Started workers")
 	<-stopCh
@@ -212,8 +348,17 @@ func (c *Controller) processNextWorkItem() bool {
 		}
 		// Run the syncHandler, passing it the namespace/name string of the
 		// TiDB resource to be synced.
-		if err := c.syncHandler(key); err != nil {
-			return fmt.Errorf("error syncing '%s': %s", key, err.Error())
+		start := time.Now()
+		c.workers.begin(key)
+		err := c.syncHandler(key)
+		c.workers.end(key)
+		syncDuration.WithLabelValues(key).Observe(time.Since(start).Seconds())
+		if err != nil {
+			syncErrors.WithLabelValues(key).Inc()
+			// Put the item back on the workqueue to handle any transient
+			// errors, it is retried after the rate limited delay.
+			c.workqueue.AddRateLimited(key)
+			return fmt.Errorf("error syncing '%s': %s, requeuing", key, err.Error())
 		}
 		// Finally, if no error occurs we Forget this item so it does not
 		// get queued again until another change happens.
@@ -240,15 +385,39 @@ func (c *Controller) updateTiDB(old, new interface{}) {
 	oldCluster := old.(*api.TiDB)
 	if newCluster.ResourceVersion == oldCluster.ResourceVersion {
 		glog.Infof("ResourceVersion not changed: %s", newCluster.ResourceVersion)
-		// Periodic resync will send update events for all known tfJobes.
-		// Two different versions of the same tfJob will always have different RVs.
+		// Periodic resync will send update events for all known TiDBs.
+		// Two different versions of the same TiDB will always have different RVs.
 		return
 	}
 	c.enqueueTiDB(newCluster)
 }
 
+// deleteTiDB is called after the finalizer is removed and the TiDB is gone,
+// the owned resources have been torn down by then.
 func (c *Controller) deleteTiDB(obj interface{}) {
-	glog.Errorln("To Be Implemented.")
+	tidb, ok := obj.(*api.TiDB)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			runtime.HandleError(fmt.Errorf("couldn't get object from tombstone %#v", obj))
+			return
+		}
+		tidb, ok = tombstone.Obj.(*api.TiDB)
+		if !ok {
+			runtime.HandleError(fmt.Errorf("tombstone contained object that is not a TiDB %#v", obj))
+			return
+		}
+	}
+
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	glog.Infof("TiDB %s deleted", key)
+	c.expectations.DeleteExpectations(key)
+	c.podExpectations.DeleteExpectations(key)
+	forgetClusterMetrics(key)
 }
 
 func (c *Controller) enqueueTiDB(obj interface{}) {
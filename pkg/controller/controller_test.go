@@ -0,0 +1,438 @@
+package controller
+
+import (
+	"testing"
+
+	apps "k8s.io/api/apps/v1beta2"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubeinformers "k8s.io/client-go/informers"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+	"github.com/gaocegege/kubetidb/pkg/clientset/versioned/fake"
+	informers "github.com/gaocegege/kubetidb/pkg/informers/externalversions"
+	"github.com/gaocegege/kubetidb/pkg/pdapi"
+	pdfake "github.com/gaocegege/kubetidb/pkg/pdapi/fake"
+	"github.com/gaocegege/kubetidb/pkg/tidbapi"
+	tidbfake "github.com/gaocegege/kubetidb/pkg/tidbapi/fake"
+)
+
+// fixture is a controller against the fake clientsets, the fake PD and the
+// fake TiDB server. The objects are put in the listers and the clientsets.
+type fixture struct {
+	t *testing.T
+
+	kubeclient *k8sfake.Clientset
+	client     *fake.Clientset
+	pd         *pdfake.Server
+	tidb       *tidbfake.Server
+	recorder   *record.FakeRecorder
+
+	controller *Controller
+}
+
+// newFixture returns a fixture with the objects, they are TiDBs or the
+// objects of Kubernetes.
+func newFixture(t *testing.T, objects ...runtime.Object) *fixture {
+	var tidbs, kubeobjects []runtime.Object
+	for _, obj := range objects {
+		if _, ok := obj.(*api.TiDB); ok {
+			tidbs = append(tidbs, obj)
+		} else {
+			kubeobjects = append(kubeobjects, obj)
+		}
+	}
+
+	f := &fixture{
+		t:          t,
+		kubeclient: k8sfake.NewSimpleClientset(kubeobjects...),
+		client:     fake.NewSimpleClientset(tidbs...),
+		pd:         pdfake.NewServer(),
+		tidb:       tidbfake.NewServer(),
+		recorder:   record.NewFakeRecorder(100),
+	}
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(f.kubeclient, 0)
+	tidbInformerFactory := informers.NewSharedInformerFactory(f.client, 0)
+	// The TiDBs are enqueued without delay.
+	c := NewController(f.kubeclient, f.client, kubeInformerFactory, tidbInformerFactory,
+		metav1.NamespaceAll, workqueue.NewItemExponentialFailureRateLimiter(0, 0))
+	c.recorder = f.recorder
+	c.pdClientFor = func(string) pdapi.Client {
+		return pdapi.NewClient(f.pd.URL, pdapi.DefaultTimeout)
+	}
+	c.tidbClientFor = func(string) tidbapi.Client {
+		return tidbapi.NewClient(f.tidb.URL, tidbapi.DefaultTimeout)
+	}
+	f.controller = c
+
+	indexers := map[string]cache.Indexer{
+		"TiDB":        tidbInformerFactory.Kubetidb().V1beta1().TiDBs().Informer().GetIndexer(),
+		"Pod":         ownedInformerFor(kubeInformerFactory, &v1.Pod{}, metav1.NamespaceAll).GetIndexer(),
+		"Service":     ownedInformerFor(kubeInformerFactory, &v1.Service{}, metav1.NamespaceAll).GetIndexer(),
+		"ConfigMap":   ownedInformerFor(kubeInformerFactory, &v1.ConfigMap{}, metav1.NamespaceAll).GetIndexer(),
+		"StatefulSet": ownedInformerFor(kubeInformerFactory, &apps.StatefulSet{}, metav1.NamespaceAll).GetIndexer(),
+		"Deployment":  ownedInformerFor(kubeInformerFactory, &apps.Deployment{}, metav1.NamespaceAll).GetIndexer(),
+	}
+	for _, obj := range objects {
+		var kind string
+		switch obj.(type) {
+		case *api.TiDB:
+			kind = "TiDB"
+		case *v1.Pod:
+			kind = "Pod"
+		case *v1.Service:
+			kind = "Service"
+		case *v1.ConfigMap:
+			kind = "ConfigMap"
+		case *apps.StatefulSet:
+			kind = "StatefulSet"
+		case *apps.Deployment:
+			kind = "Deployment"
+		default:
+			continue
+		}
+		if err := indexers[kind].Add(obj); err != nil {
+			t.Fatalf("Failed to add %s to the lister: %v", kind, err)
+		}
+	}
+	return f
+}
+
+// close stops the fake servers.
+func (f *fixture) close() {
+	f.pd.Close()
+	f.tidb.Close()
+}
+
+// sync syncs the TiDB and fails the test on errors.
+func (f *fixture) sync(tidb *api.TiDB) {
+	if err := f.controller.syncHandler(getKey(f.t, tidb)); err != nil {
+		f.t.Fatalf("Failed to sync: %v", err)
+	}
+}
+
+// getTiDB returns the TiDB in the fake clientset.
+func (f *fixture) getTiDB(tidb *api.TiDB) *api.TiDB {
+	updated, err := f.client.KubetidbV1beta1().TiDBs(tidb.Namespace).Get(tidb.Name, metav1.GetOptions{})
+	if err != nil {
+		f.t.Fatalf("Failed to get TiDB %s: %v", tidb.Name, err)
+	}
+	return updated
+}
+
+// getStatefulSet returns the statefulset in the fake clientset.
+func (f *fixture) getStatefulSet(name string) *apps.StatefulSet {
+	statefulSet, err := f.kubeclient.AppsV1beta2().StatefulSets(metav1.NamespaceDefault).Get(name, metav1.GetOptions{})
+	if err != nil {
+		f.t.Fatalf("Failed to get statefulset %s: %v", name, err)
+	}
+	return statefulSet
+}
+
+// created returns the objects created by the controller in the fake
+// clientset, they are the objects the informers observe.
+func (f *fixture) created() []runtime.Object {
+	var objects []runtime.Object
+	for _, action := range f.kubeclient.Actions() {
+		if create, ok := action.(interface {
+			GetVerb() string
+			GetObject() runtime.Object
+		}); ok && create.GetVerb() == "create" {
+			objects = append(objects, create.GetObject())
+		}
+	}
+	return objects
+}
+
+// deleted returns the names of the objects of the resource deleted by the
+// controller in the fake clientset.
+func (f *fixture) deleted(resource string) []string {
+	var names []string
+	for _, action := range f.kubeclient.Actions() {
+		if deletion, ok := action.(interface {
+			GetName() string
+			Matches(verb, resource string) bool
+		}); ok && deletion.Matches("delete", resource) {
+			names = append(names, deletion.GetName())
+		}
+	}
+	return names
+}
+
+func newTiDB(name string) *api.TiDB {
+	tidb := &api.TiDB{
+		TypeMeta: metav1.TypeMeta{APIVersion: api.SchemeGroupVersion.String(), Kind: api.ResourceKind},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: metav1.NamespaceDefault,
+			UID:       types.UID(name + "-uid"),
+		},
+	}
+	return tidb
+}
+
+func getKey(t *testing.T, tidb *api.TiDB) string {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		t.Fatalf("Failed to get the key of TiDB %s: %v", tidb.Name, err)
+	}
+	return key
+}
+
+// checkOwned checks the object is controlled by the TiDB.
+func checkOwned(t *testing.T, object metav1.Object, tidb *api.TiDB) {
+	ref := metav1.GetControllerOf(object)
+	if ref == nil {
+		t.Errorf("Expected %s to be controlled by TiDB %s, got no controller", object.GetName(), tidb.Name)
+		return
+	}
+	if ref.Kind != api.ResourceKind || ref.Name != tidb.Name || ref.UID != tidb.UID || ref.APIVersion != api.SchemeGroupVersion.String() {
+		t.Errorf("Expected %s to be controlled by TiDB %s, got %+v", object.GetName(), tidb.Name, ref)
+	}
+	if object.GetLabels()[labelCluster] != tidb.Name {
+		t.Errorf("Expected %s to be labelled with cluster %s, got %v", object.GetName(), tidb.Name, object.GetLabels())
+	}
+}
+
+func TestSyncCreatesCluster(t *testing.T) {
+	tidb := newTiDB("foo")
+	f := newFixture(t, tidb)
+	defer f.close()
+
+	f.sync(tidb)
+
+	updated := f.getTiDB(tidb)
+	if !hasFinalizer(updated) {
+		t.Errorf("Expected the finalizer to be added, got %v", updated.Finalizers)
+	}
+	if updated.Status.StartTime == nil {
+		t.Errorf("Expected the status to be written, got %+v", updated.Status)
+	}
+
+	pd := f.getStatefulSet("foo-pd")
+	checkOwned(t, pd, tidb)
+	if replicas := getReplicas(pd.Spec.Replicas); replicas != 1 {
+		t.Errorf("Expected 1 PD member, got %d", replicas)
+	}
+	if pd.Annotations[annotationPDInitialReplicas] != "1" {
+		t.Errorf("Expected the initial replicas to be recorded, got %v", pd.Annotations)
+	}
+	if !hasVolumeClaimTemplate(pd, pdDataVolume) {
+		t.Errorf("Expected the PD data claim template, got %+v", pd.Spec.VolumeClaimTemplates)
+	}
+	if claim := pd.Spec.VolumeClaimTemplates[0]; claim.Labels[labelCluster] != tidb.Name {
+		t.Errorf("Expected the PD data claim to be labelled with the cluster, got %v", claim.Labels)
+	}
+
+	tikv := f.getStatefulSet("foo-tikv")
+	checkOwned(t, tikv, tidb)
+	if replicas := getReplicas(tikv.Spec.Replicas); replicas != api.DefaultTiKVReplicas {
+		t.Errorf("Expected %d TiKV stores, got %d", api.DefaultTiKVReplicas, replicas)
+	}
+
+	deployment, err := f.kubeclient.AppsV1beta2().Deployments(metav1.NamespaceDefault).Get("foo-tidb", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get the TiDB deployment: %v", err)
+	}
+	checkOwned(t, deployment, tidb)
+
+	for _, name := range []string{"foo-pd", "foo-pd-peer", "foo-tikv-peer", "foo-tidb"} {
+		service, err := f.kubeclient.CoreV1().Services(metav1.NamespaceDefault).Get(name, metav1.GetOptions{})
+		if err != nil {
+			t.Errorf("Failed to get service %s: %v", name, err)
+			continue
+		}
+		checkOwned(t, service, tidb)
+	}
+	if peer, _ := f.kubeclient.CoreV1().Services(metav1.NamespaceDefault).Get("foo-pd-peer", metav1.GetOptions{}); peer != nil &&
+		peer.Spec.ClusterIP != v1.ClusterIPNone {
+		t.Errorf("Expected the PD peer service to be headless, got %q", peer.Spec.ClusterIP)
+	}
+}
+
+func TestSyncWaitsForExpectations(t *testing.T) {
+	tidb := newTiDB("foo")
+	f := newFixture(t, tidb)
+	defer f.close()
+
+	f.sync(tidb)
+	key := getKey(t, tidb)
+	created := f.created()
+	if len(created) != 7 {
+		t.Fatalf("Expected 7 objects to be created, got %d", len(created))
+	}
+	if f.controller.expectations.SatisfiedExpectations(key) {
+		t.Fatalf("Expected the creations to be expected")
+	}
+
+	// The cluster is not synced again until the creations are observed.
+	f.kubeclient.ClearActions()
+	f.sync(tidb)
+	if actions := f.kubeclient.Actions(); len(actions) != 0 {
+		t.Errorf("Expected no actions before the creations are observed, got %v", actions)
+	}
+
+	for _, obj := range created {
+		f.controller.addOwned(obj)
+	}
+	if !f.controller.expectations.SatisfiedExpectations(key) {
+		t.Errorf("Expected the expectations to be satisfied after the creations are observed")
+	}
+	if f.controller.workqueue.Len() != 1 {
+		t.Errorf("Expected the TiDB to be enqueued once, got %d", f.controller.workqueue.Len())
+	}
+}
+
+func TestSyncUpdatesStatefulSet(t *testing.T) {
+	tidb := newTiDB("foo")
+	f := newFixture(t, tidb)
+	f.sync(tidb)
+	created := f.created()
+	f.close()
+
+	// A controller whose listers have the objects created by the first
+	// sync.
+	tidb = f.getTiDB(tidb)
+	replicas := int32(3)
+	tidb.Spec.PDSpec.Replicas = &replicas
+	f = newFixture(t, append(created, tidb)...)
+	defer f.close()
+	f.sync(tidb)
+
+	pd := f.getStatefulSet("foo-pd")
+	if got := getReplicas(pd.Spec.Replicas); got != 3 {
+		t.Errorf("Expected the PD statefulset to be scaled out to 3, got %d", got)
+	}
+	if pd.Annotations[annotationPDInitialReplicas] != "1" {
+		t.Errorf("Expected the initial replicas to be kept, got %v", pd.Annotations)
+	}
+	for _, action := range f.kubeclient.Actions() {
+		if action.GetVerb() == "create" {
+			t.Errorf("Expected no creations, got %v", action)
+		}
+	}
+}
+
+func TestSyncScalesPDIn(t *testing.T) {
+	tidb := newTiDB("foo")
+	replicas := int32(5)
+	tidb.Spec.PDSpec.Replicas = &replicas
+	f := newFixture(t, tidb)
+	f.sync(tidb)
+	created := f.created()
+	f.close()
+
+	tidb = f.getTiDB(tidb)
+	replicas = 3
+	tidb.Spec.PDSpec.Replicas = &replicas
+	f = newFixture(t, append(created, tidb)...)
+	defer f.close()
+	for i := 0; i < 5; i++ {
+		f.pd.AddMember(genPodName(tidb, componentPD, i), true)
+	}
+	f.sync(tidb)
+
+	if got := getReplicas(f.getStatefulSet("foo-pd").Spec.Replicas); got != 4 {
+		t.Errorf("Expected the PD statefulset to be scaled in by one, got %d", got)
+	}
+	for _, member := range f.pd.Members() {
+		if member == "foo-pd-4" {
+			t.Errorf("Expected member foo-pd-4 to be deleted, got %v", f.pd.Members())
+		}
+	}
+	record := f.getTiDB(tidb).Status.PD.ScalingIn
+	if record == nil || record.PodName != "foo-pd-4" {
+		t.Errorf("Expected the removal of foo-pd-4 to be recorded, got %+v", record)
+	}
+}
+
+func TestSyncRefusesPDScaleInWithoutQuorum(t *testing.T) {
+	tidb := newTiDB("foo")
+	replicas := int32(5)
+	tidb.Spec.PDSpec.Replicas = &replicas
+	f := newFixture(t, tidb)
+	f.sync(tidb)
+	created := f.created()
+	f.close()
+
+	tidb = f.getTiDB(tidb)
+	replicas = 3
+	tidb.Spec.PDSpec.Replicas = &replicas
+	f = newFixture(t, append(created, tidb)...)
+	defer f.close()
+	for i := 0; i < 5; i++ {
+		f.pd.AddMember(genPodName(tidb, componentPD, i), i < 2)
+	}
+	f.sync(tidb)
+
+	if got := getReplicas(f.getStatefulSet("foo-pd").Spec.Replicas); got != 5 {
+		t.Errorf("Expected the PD statefulset not to be scaled in, got %d", got)
+	}
+	if len(f.pd.Members()) != 5 {
+		t.Errorf("Expected no member to be deleted, got %v", f.pd.Members())
+	}
+}
+
+func TestSyncRefusesOwnedByOthers(t *testing.T) {
+	tidb := newTiDB("foo")
+	service := newService(newTiDB("bar"), componentPD, "foo-pd", nil)
+	f := newFixture(t, tidb, service)
+	defer f.close()
+
+	if err := f.controller.syncHandler(getKey(t, tidb)); err == nil {
+		t.Errorf("Expected an error for the service owned by another TiDB")
+	}
+}
+
+func TestOwnedEvents(t *testing.T) {
+	tidb := newTiDB("foo")
+	f := newFixture(t, tidb)
+	defer f.close()
+	key := getKey(t, tidb)
+	c := f.controller
+
+	// The object created by the controller is observed.
+	c.expectations.ExpectCreations(key, 1)
+	service := newService(tidb, componentPD, "foo-pd", nil)
+	c.addOwned(service)
+	if !c.expectations.SatisfiedExpectations(key) {
+		t.Errorf("Expected the creation to be observed")
+	}
+
+	// The pods of the statefulsets are resolved by the cluster label, they
+	// are not expected.
+	c.expectations.ExpectCreations(key, 1)
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "foo-pd-0",
+		Namespace: metav1.NamespaceDefault,
+		Labels:    genLabels(tidb, componentPD),
+	}}
+	c.addOwned(pod)
+	if c.expectations.SatisfiedExpectations(key) {
+		t.Errorf("Expected the pod not to be observed as a creation")
+	}
+	c.expectations.CreationObserved(key)
+
+	// The object controlled by another TiDB with the same name is ignored.
+	c.expectations.ExpectDeletions(key, 1)
+	other := newService(newTiDB("foo"), componentPD, "foo-pd", nil)
+	other.OwnerReferences[0].UID = "other"
+	c.deleteOwned(other)
+	if c.expectations.SatisfiedExpectations(key) {
+		t.Errorf("Expected the object of another TiDB not to be observed")
+	}
+	c.deleteOwned(cache.DeletedFinalStateUnknown{Key: "default/foo-pd", Obj: service})
+	if !c.expectations.SatisfiedExpectations(key) {
+		t.Errorf("Expected the deletion to be observed from the tombstone")
+	}
+
+	if c.workqueue.Len() != 1 {
+		t.Errorf("Expected the TiDB to be enqueued once, got %d", c.workqueue.Len())
+	}
+}
@@ -0,0 +1,243 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	apps "k8s.io/api/apps/v1beta2"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+const (
+	// finalizerName is the finalizer of TiDB clusters, it is removed after
+	// all the owned resources are torn down in order.
+	finalizerName = "kubetidb.gaocegege.com/teardown"
+
+	// TearingDown is used as part of the Event 'reason' when a component of
+	// a TiDB is being torn down
+	TearingDown = "TearingDown"
+	// SuccessTornDown is used as part of the Event 'reason' when all the
+	// resources of a TiDB are torn down
+	SuccessTornDown = "TornDown"
+)
+
+// teardownOrder is the order to tear down the components. TiDB servers go
+// first so no more requests come in, PD goes last since TiKV stores report
+// to it.
+var teardownOrder = []componentType{componentTiDB, componentTiKV, componentPD}
+
+// hasFinalizer returns true if the TiDB has the kubetidb finalizer.
+func hasFinalizer(tidb *api.TiDB) bool {
+	for _, f := range tidb.Finalizers {
+		if f == finalizerName {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureFinalizer adds the kubetidb finalizer to the TiDB if it is missing.
+func (c *Controller) ensureFinalizer(tidb *api.TiDB) (*api.TiDB, error) {
+	if hasFinalizer(tidb) {
+		return tidb, nil
+	}
+	tidbCopy := tidb.DeepCopy()
+	tidbCopy.Finalizers = append(tidbCopy.Finalizers, finalizerName)
+	return c.tidbClientset.KubetidbV1beta1().TiDBs(tidb.Namespace).Update(tidbCopy)
+}
+
+// removeFinalizer removes the kubetidb finalizer from the TiDB, so it could
+// be deleted by the API server.
+func (c *Controller) removeFinalizer(tidb *api.TiDB) error {
+	tidbCopy := tidb.DeepCopy()
+	tidbCopy.Finalizers = nil
+	for _, f := range tidb.Finalizers {
+		if f != finalizerName {
+			tidbCopy.Finalizers = append(tidbCopy.Finalizers, f)
+		}
+	}
+	_, err := c.tidbClientset.KubetidbV1beta1().TiDBs(tidb.Namespace).Update(tidbCopy)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// teardownCluster deletes the owned resources component by component in the
+// teardown order. The next component is not touched until all the
+// resources of the previous one are gone, and the finalizer is removed
+// at last.
+func (c *Controller) teardownCluster(tidb *api.TiDB) error {
+	if !hasFinalizer(tidb) {
+		return nil
+	}
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return err
+	}
+	if err := c.updateStatus(tidb); err != nil {
+		return err
+	}
+
+	for _, component := range teardownOrder {
+		done, err := c.teardownComponent(tidb, component)
+		if err != nil {
+			return err
+		}
+		if !done {
+			// Wait for the events of the owned resources to enqueue the
+			// TiDB again.
+			glog.V(4).Infof("Waiting for %s of %s to be torn down", component, key)
+			return nil
+		}
+	}
+
+	if !tidb.Spec.RetainPVCs {
+		if err := c.deletePVCs(tidb); err != nil {
+			return err
+		}
+	}
+
+	if err := c.removeFinalizer(tidb); err != nil {
+		return err
+	}
+	c.expectations.DeleteExpectations(key)
+	c.podExpectations.DeleteExpectations(key)
+	c.recorder.Event(tidb, v1.EventTypeNormal, SuccessTornDown, "All the resources are torn down")
+	return nil
+}
+
+// teardownComponent deletes the workloads, services and config maps of the
+// component. It returns true if all of them and the pods are gone.
+func (c *Controller) teardownComponent(tidb *api.TiDB, component componentType) (bool, error) {
+	statefulSets, err := c.statefulSetLister.StatefulSets(tidb.Namespace).List(genSelector(tidb, component))
+	if err != nil {
+		return false, err
+	}
+	deployments, err := c.deploymentLister.Deployments(tidb.Namespace).List(genSelector(tidb, component))
+	if err != nil {
+		return false, err
+	}
+	pods, err := c.podLister.Pods(tidb.Namespace).List(genSelector(tidb, component))
+	if err != nil {
+		return false, err
+	}
+	services, err := c.serviceLister.Services(tidb.Namespace).List(genSelector(tidb, component))
+	if err != nil {
+		return false, err
+	}
+	configMaps, err := c.configMapLister.ConfigMaps(tidb.Namespace).List(genSelector(tidb, component))
+	if err != nil {
+		return false, err
+	}
+	statefulSets = filterOwnedStatefulSets(tidb, statefulSets)
+	deployments = filterOwnedDeployments(tidb, deployments)
+	services = filterOwnedServices(tidb, services)
+	configMaps = filterOwnedConfigMaps(tidb, configMaps)
+	if len(statefulSets) == 0 && len(deployments) == 0 && len(pods) == 0 && len(services) == 0 && len(configMaps) == 0 {
+		return true, nil
+	}
+
+	c.recorder.Eventf(tidb, v1.EventTypeNormal, TearingDown, "Tearing down %s", component)
+	for _, statefulSet := range statefulSets {
+		if statefulSet.DeletionTimestamp != nil {
+			continue
+		}
+		if err := c.deleteStatefulSet(tidb, statefulSet); err != nil {
+			return false, err
+		}
+	}
+	for _, deployment := range deployments {
+		if deployment.DeletionTimestamp != nil {
+			continue
+		}
+		if err := c.deleteDeployment(tidb, deployment); err != nil {
+			return false, err
+		}
+	}
+	for _, service := range services {
+		if service.DeletionTimestamp != nil {
+			continue
+		}
+		if err := c.deleteService(tidb, service); err != nil {
+			return false, err
+		}
+	}
+	for _, configMap := range configMaps {
+		if configMap.DeletionTimestamp != nil {
+			continue
+		}
+		if err := c.deleteConfigMap(tidb, configMap); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// deletePVCs deletes the persistent volume claims of the cluster.
+func (c *Controller) deletePVCs(tidb *api.TiDB) error {
+	selector := labels.SelectorFromSet(map[string]string{labelCluster: tidb.Name})
+	pvcs, err := c.kubeclientset.CoreV1().PersistentVolumeClaims(tidb.Namespace).List(metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return err
+	}
+	for _, pvc := range pvcs.Items {
+		err := c.kubeclientset.CoreV1().PersistentVolumeClaims(tidb.Namespace).Delete(pvc.Name, &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete persistent volume claim %s: %v", pvc.Name, err)
+		}
+	}
+	return nil
+}
+
+// filterOwnedStatefulSets returns the statefulsets controlled by the TiDB.
+func filterOwnedStatefulSets(tidb *api.TiDB, statefulSets []*apps.StatefulSet) []*apps.StatefulSet {
+	var owned []*apps.StatefulSet
+	for _, statefulSet := range statefulSets {
+		if metav1.IsControlledBy(statefulSet, tidb) {
+			owned = append(owned, statefulSet)
+		}
+	}
+	return owned
+}
+
+// filterOwnedDeployments returns the deployments controlled by the TiDB.
+func filterOwnedDeployments(tidb *api.TiDB, deployments []*apps.Deployment) []*apps.Deployment {
+	var owned []*apps.Deployment
+	for _, deployment := range deployments {
+		if metav1.IsControlledBy(deployment, tidb) {
+			owned = append(owned, deployment)
+		}
+	}
+	return owned
+}
+
+// filterOwnedServices returns the services controlled by the TiDB.
+func filterOwnedServices(tidb *api.TiDB, services []*v1.Service) []*v1.Service {
+	var owned []*v1.Service
+	for _, service := range services {
+		if metav1.IsControlledBy(service, tidb) {
+			owned = append(owned, service)
+		}
+	}
+	return owned
+}
+
+// filterOwnedConfigMaps returns the config maps controlled by the TiDB.
+func filterOwnedConfigMaps(tidb *api.TiDB, configMaps []*v1.ConfigMap) []*v1.ConfigMap {
+	var owned []*v1.ConfigMap
+	for _, configMap := range configMaps {
+		if metav1.IsControlledBy(configMap, tidb) {
+			owned = append(owned, configMap)
+		}
+	}
+	return owned
+}
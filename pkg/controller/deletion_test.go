@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestSyncTearsDownCluster(t *testing.T) {
+	tidb, objects := newRunningCluster(t, newTiDB("foo"))
+	if !hasFinalizer(tidb) {
+		t.Fatalf("Expected the finalizer to be added, got %v", tidb.Finalizers)
+	}
+	now := metav1.Now()
+	tidb.DeletionTimestamp = &now
+	objects = append(objects, &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:      pdDataVolume + "-foo-pd-0",
+		Namespace: tidb.Namespace,
+		Labels:    map[string]string{labelCluster: tidb.Name},
+	}})
+
+	// The components are torn down one by one, the next one is not touched
+	// until the objects of the previous one are gone.
+	for _, component := range teardownOrder {
+		var expected, remaining []runtime.Object
+		var expectedNames []string
+		for _, obj := range objects {
+			accessor := obj.(metav1.Object)
+			if accessor.GetLabels()[labelComponent] != string(component) {
+				remaining = append(remaining, obj)
+				continue
+			}
+			expected = append(expected, obj)
+			if _, ok := obj.(*v1.Pod); !ok {
+				expectedNames = append(expectedNames, accessor.GetName())
+			}
+		}
+		if len(expected) == 0 {
+			t.Fatalf("Expected the objects of %s in the running cluster", component)
+		}
+
+		f := newFixture(t, append([]runtime.Object{tidb}, objects...)...)
+		f.sync(tidb)
+		var deleted []string
+		for _, resource := range []string{"statefulsets", "deployments", "services", "configmaps", "pods", "persistentvolumeclaims"} {
+			deleted = append(deleted, f.deleted(resource)...)
+		}
+		sort.Strings(deleted)
+		sort.Strings(expectedNames)
+		if !reflect.DeepEqual(deleted, expectedNames) {
+			t.Errorf("Expected %s to be torn down with %v, got %v", component, expectedNames, deleted)
+		}
+		if !hasFinalizer(f.getTiDB(tidb)) {
+			t.Errorf("Expected the finalizer to be kept while %s is torn down", component)
+		}
+		f.close()
+		// The pods are deleted by the garbage collector.
+		objects = remaining
+	}
+
+	f := newFixture(t, append([]runtime.Object{tidb}, objects...)...)
+	defer f.close()
+	f.sync(tidb)
+	if deleted := f.deleted("persistentvolumeclaims"); !reflect.DeepEqual(deleted, []string{pdDataVolume + "-foo-pd-0"}) {
+		t.Errorf("Expected the claims of the cluster to be deleted, got %v", deleted)
+	}
+	if updated := f.getTiDB(tidb); hasFinalizer(updated) {
+		t.Errorf("Expected the finalizer to be removed, got %v", updated.Finalizers)
+	}
+	if got := countEvents(f, SuccessTornDown); got != 1 {
+		t.Errorf("Expected the teardown to be reported once, got %d", got)
+	}
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	apps "k8s.io/api/apps/v1beta2"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+// syncDeployment creates the deployment if it does not exist, or updates it
+// if the rendered spec changed.
+func (c *Controller) syncDeployment(tidb *api.TiDB, desired *apps.Deployment) error {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return err
+	}
+	setSpecHash(&desired.ObjectMeta, desired.Spec)
+
+	existing, err := c.deploymentLister.Deployments(tidb.Namespace).Get(desired.Name)
+	if errors.IsNotFound(err) {
+		c.raiseExpectations(key, 1, 0)
+		if _, err := c.kubeclientset.AppsV1beta2().Deployments(tidb.Namespace).Create(desired); err != nil {
+			// The deployment informer won't observe the creation, so
+			// decrement the expected number of creates.
+			c.expectations.CreationObserved(key)
+			if !errors.IsAlreadyExists(err) {
+				return fmt.Errorf("failed to create deployment %s: %v", desired.Name, err)
+			}
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(existing, tidb) {
+		return fmt.Errorf("deployment %s already exists and is not owned by %s", existing.Name, key)
+	}
+
+	if existing.Annotations[annotationSpecHash] == desired.Annotations[annotationSpecHash] {
+		return nil
+	}
+	glog.V(4).Infof("Update deployment %s/%s", existing.Namespace, existing.Name)
+	deployment := existing.DeepCopy()
+	deployment.Annotations = mergeAnnotations(deployment.Annotations, desired.Annotations)
+	deployment.Spec.Replicas = desired.Spec.Replicas
+	deployment.Spec.Template = desired.Spec.Template
+	deployment.Spec.Strategy = desired.Spec.Strategy
+	deployment.Spec.Paused = desired.Spec.Paused
+	_, err = c.kubeclientset.AppsV1beta2().Deployments(tidb.Namespace).Update(deployment)
+	return err
+}
+
+// deleteDeployment deletes the deployment owned by the TiDB, the replica
+// sets and pods are deleted by the garbage collector.
+func (c *Controller) deleteDeployment(tidb *api.TiDB, deployment *apps.Deployment) error {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return err
+	}
+
+	propagation := metav1.DeletePropagationBackground
+	c.raiseExpectations(key, 0, 1)
+	err = c.kubeclientset.AppsV1beta2().Deployments(tidb.Namespace).Delete(deployment.Name, &metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if err != nil {
+		// The deployment informer won't observe the deletion, so decrement
+		// the expected number of deletes.
+		c.expectations.DeletionObserved(key)
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete deployment %s: %v", deployment.Name, err)
+		}
+	}
+	return nil
+}
+
+// newDeployment returns the deployment of the component which is owned by
+// the TiDB cluster.
+func newDeployment(tidb *api.TiDB, component componentType, replicas int, template *v1.PodTemplateSpec) *apps.Deployment {
+	replicasInt32 := int32(replicas)
+	return &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            genName(tidb, component),
+			Namespace:       tidb.Namespace,
+			Labels:          genLabels(tidb, component),
+			OwnerReferences: []metav1.OwnerReference{*genOwnerReference(tidb)},
+		},
+		Spec: apps.DeploymentSpec{
+			Replicas: &replicasInt32,
+			Selector: &metav1.LabelSelector{MatchLabels: genLabels(tidb, component)},
+			Template: *template,
+		},
+	}
+}
@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+	"github.com/gaocegege/kubetidb/pkg/pdapi"
+)
+
+const (
+	// EvictingLeader is used as part of the Event 'reason' when the region
+	// leaders are evicted from a TiKV store before its pod is upgraded.
+	EvictingLeader = "EvictingLeader"
+	// EvictLeaderTimeout is used as part of the Event 'reason' when a TiKV
+	// pod is upgraded before all the region leaders are evicted.
+	EvictLeaderTimeout = "EvictLeaderTimeout"
+	// EvictLeaderFinished is used as part of the Event 'reason' when the
+	// scheduler evicting the region leaders from a TiKV store is removed.
+	EvictLeaderFinished = "EvictLeaderFinished"
+
+	// evictLeaderTimeout is how long the region leaders are evicted from a
+	// TiKV store before its pod is upgraded anyway.
+	evictLeaderTimeout = 5 * time.Minute
+)
+
+// A TiKV pod is not deleted right away to be upgraded. An evict leader
+// scheduler is added in PD for its store first, so that the region leaders
+// are moved to the other stores and the requests are not interrupted. The
+// eviction is recorded in the status before the scheduler is added, it is
+// resumed on every sync until the recreated pod is healthy and the scheduler
+// is removed again.
+
+// startEvictingLeader records the eviction of the region leaders from the
+// store of the pod, and adds the scheduler evicting them.
+func (c *Controller) startEvictingLeader(tidb *api.TiDB, pod *v1.Pod) error {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return err
+	}
+	client := c.getPDClient(tidb)
+	storeID, err := getTiKVStoreID(client, genTiKVAddr(tidb, pod.Name))
+	if err != nil {
+		return err
+	}
+
+	tidb.Status.TiKV.EvictingLeader = &api.EvictLeaderStatus{
+		PodName:   pod.Name,
+		PodUID:    pod.UID,
+		StoreID:   storeID,
+		StartTime: metav1.Now(),
+	}
+	if err := c.writeStatus(tidb); err != nil {
+		return err
+	}
+	c.recorder.Eventf(tidb, v1.EventTypeNormal, EvictingLeader,
+		"Evicting region leaders from store %d of pod %s", storeID, pod.Name)
+	if err := ensureEvictLeaderScheduler(client, storeID); err != nil {
+		return err
+	}
+	c.workqueue.AddAfter(key, upgradeRetryInterval)
+	return nil
+}
+
+// syncEvictingLeader resumes the recorded eviction. The pod is deleted once
+// its store has no region leader or the eviction timed out. After the pod is
+// recreated and all the stores are up, the scheduler is removed and the
+// record is cleared. The eviction is cancelled if the cluster is paused or
+// the pod does not need to be upgraded anymore.
+func (c *Controller) syncEvictingLeader(tidb *api.TiDB, progress *upgradeProgress) error {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return err
+	}
+	if !c.podExpectations.SatisfiedExpectations(key) {
+		return nil
+	}
+	record := tidb.Status.TiKV.EvictingLeader
+	client := c.getPDClient(tidb)
+
+	var pod *v1.Pod
+	for _, p := range progress.pods {
+		if p.Name == record.PodName {
+			pod = p
+		}
+	}
+	restarted := pod == nil || pod.UID != record.PodUID
+	outdated := false
+	for _, p := range progress.outdated {
+		if p.UID == record.PodUID {
+			outdated = true
+		}
+	}
+
+	if !restarted && outdated && !tidb.Spec.Paused {
+		if pod.DeletionTimestamp != nil {
+			// The pod is terminating, the statefulset recreates it.
+			return nil
+		}
+		if err := ensureEvictLeaderScheduler(client, record.StoreID); err != nil {
+			return err
+		}
+		store, err := client.GetStore(record.StoreID)
+		if err != nil {
+			return fmt.Errorf("failed to get store %d from PD: %v", record.StoreID, err)
+		}
+		leaders := 0
+		if store.Status != nil {
+			leaders = store.Status.LeaderCount
+		}
+		if leaders > 0 {
+			if time.Since(record.StartTime.Time) < evictLeaderTimeout {
+				glog.V(4).Infof("Waiting for %d region leaders to be evicted from store %d of TiDB %s", leaders, record.StoreID, key)
+				c.workqueue.AddAfter(key, upgradeRetryInterval)
+				return nil
+			}
+			c.recorder.Eventf(tidb, v1.EventTypeWarning, EvictLeaderTimeout,
+				"Store %d of pod %s still has %d region leaders after %v", record.StoreID, pod.Name, leaders, evictLeaderTimeout)
+		}
+		return c.deleteUpgradingPod(tidb, pod, componentTiKV)
+	}
+
+	if restarted && !tidb.Spec.Paused {
+		// The leaders are not evicted from the recreated store until it
+		// serves requests again.
+		if progress.statefulSet == nil || len(progress.pods) != getReplicas(progress.statefulSet.Spec.Replicas) {
+			return nil
+		}
+		for _, p := range progress.pods {
+			if !isPodReady(p) {
+				return nil
+			}
+		}
+		if err := c.checkTiKVHealth(tidb, progress.pods); err != nil {
+			glog.Infof("Waiting for TiKV of TiDB %s to be healthy: %v", key, err)
+			c.workqueue.AddAfter(key, upgradeRetryInterval)
+			return nil
+		}
+	}
+
+	schedulers, err := client.GetSchedulers()
+	if err != nil {
+		return fmt.Errorf("failed to get the schedulers from PD: %v", err)
+	}
+	if hasScheduler(schedulers, pdapi.EvictLeaderSchedulerName(record.StoreID)) {
+		if err := client.RemoveEvictLeaderScheduler(record.StoreID); err != nil {
+			return fmt.Errorf("failed to remove the evict leader scheduler of store %d: %v", record.StoreID, err)
+		}
+	}
+	tidb.Status.TiKV.EvictingLeader = nil
+	if err := c.writeStatus(tidb); err != nil {
+		return err
+	}
+	c.recorder.Eventf(tidb, v1.EventTypeNormal, EvictLeaderFinished,
+		"Stopped evicting region leaders from store %d of pod %s", record.StoreID, record.PodName)
+	return nil
+}
+
+// ensureEvictLeaderScheduler adds the scheduler evicting the region leaders
+// from the store unless it is running.
+func ensureEvictLeaderScheduler(client pdapi.Client, storeID uint64) error {
+	schedulers, err := client.GetSchedulers()
+	if err != nil {
+		return fmt.Errorf("failed to get the schedulers from PD: %v", err)
+	}
+	if hasScheduler(schedulers, pdapi.EvictLeaderSchedulerName(storeID)) {
+		return nil
+	}
+	if err := client.AddEvictLeaderScheduler(storeID); err != nil {
+		return fmt.Errorf("failed to add the evict leader scheduler of store %d: %v", storeID, err)
+	}
+	return nil
+}
+
+// getTiKVStoreID returns the ID of the store advertising the address.
+func getTiKVStoreID(client pdapi.Client, addr string) (uint64, error) {
+	store, err := getTiKVStore(client, addr)
+	if err != nil {
+		return 0, err
+	}
+	if store == nil {
+		return 0, fmt.Errorf("no store of address %s in PD", addr)
+	}
+	return store.Store.ID, nil
+}
+
+func hasScheduler(schedulers []string, name string) bool {
+	for _, scheduler := range schedulers {
+		if scheduler == name {
+			return true
+		}
+	}
+	return false
+}
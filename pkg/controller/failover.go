@@ -0,0 +1,430 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+	"github.com/gaocegege/kubetidb/pkg/pdapi"
+)
+
+const (
+	// TiKVStoreFailed is used as part of the Event 'reason' when a TiKV
+	// store is observed unhealthy.
+	TiKVStoreFailed = "TiKVStoreFailed"
+	// TiKVFailover is used as part of the Event 'reason' when a replacement
+	// store is added for an unhealthy TiKV store.
+	TiKVFailover = "TiKVFailover"
+	// TiKVStoreRecovered is used as part of the Event 'reason' when an
+	// unhealthy TiKV store is healthy again.
+	TiKVStoreRecovered = "TiKVStoreRecovered"
+
+	// PDMemberFailed is used as part of the Event 'reason' when a PD member
+	// is observed unhealthy.
+	PDMemberFailed = "PDMemberFailed"
+	// PDFailover is used as part of the Event 'reason' when an unhealthy PD
+	// member is deleted and its pod is recreated.
+	PDFailover = "PDFailover"
+	// PDFailoverRefused is used as part of the Event 'reason' when deleting
+	// an unhealthy PD member would break the quorum.
+	PDFailoverRefused = "PDFailoverRefused"
+	// PDMemberRecovered is used as part of the Event 'reason' when an
+	// unhealthy PD member is healthy again.
+	PDMemberRecovered = "PDMemberRecovered"
+
+	// TiDBServerFailed is used as part of the Event 'reason' when a TiDB
+	// server is observed unhealthy.
+	TiDBServerFailed = "TiDBServerFailed"
+	// TiDBFailover is used as part of the Event 'reason' when an extra TiDB
+	// server is added for an unhealthy one.
+	TiDBFailover = "TiDBFailover"
+	// TiDBServerRecovered is used as part of the Event 'reason' when an
+	// unhealthy TiDB server is healthy again or gone.
+	TiDBServerRecovered = "TiDBServerRecovered"
+
+	// failureReasonPodNotReady is the reason of the failure stores whose
+	// pods are not ready.
+	failureReasonPodNotReady = "PodNotReady"
+	// failureReasonStoreDown is the reason of the failure stores reported
+	// down by PD, i.e. without heartbeats beyond max-store-down-time.
+	failureReasonStoreDown = "StoreDown"
+)
+
+// The TiKV stores of the desired replicas are checked on every sync, the
+// unhealthy ones are recorded in the status as failure stores. A replacement
+// store is added by scaling out the statefulset for every store unhealthy
+// beyond the failover grace period, so that PD could move the replicas of
+// the regions onto it. Once the store recovers, the replacement is removed
+// through PD like any other store scaled in.
+
+// syncTiKVFailover updates the failure stores in the status from the pods
+// and the stores in PD, it returns the number of the replacement stores.
+func (c *Controller) syncTiKVFailover(tidb *api.TiDB) (int, error) {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return 0, err
+	}
+	spec := tidb.Spec.TiKVSpec
+	gracePeriod := api.DefaultTiKVFailoverGracePeriod
+	if spec.FailoverGracePeriod != nil {
+		gracePeriod = spec.FailoverGracePeriod.Duration
+	}
+
+	pods, err := c.podLister.Pods(tidb.Namespace).List(genSelector(tidb, componentTiKV))
+	if err != nil {
+		return 0, err
+	}
+	podsByName := make(map[string]*v1.Pod)
+	for _, pod := range pods {
+		podsByName[pod.Name] = pod
+	}
+	stores := make(map[string]*pdapi.StoreInfo)
+	if info, err := c.getPDClient(tidb).GetStores(); err != nil {
+		// PD may be unavailable, the stores are checked by the pods only.
+		glog.Warningf("Failed to get the stores of TiDB %s from PD: %v", key, err)
+	} else {
+		for _, store := range info.Stores {
+			if store.Store != nil {
+				stores[store.Store.Address] = store
+			}
+		}
+	}
+	previous := make(map[string]api.FailureStore)
+	for _, failure := range tidb.Status.TiKV.FailureStores {
+		previous[failure.PodName] = failure
+	}
+
+	var failures []api.FailureStore
+	replacements := 0
+	now := metav1.Now()
+	for i := 0; i < getReplicas(spec.Replicas); i++ {
+		name := genPodName(tidb, componentTiKV, i)
+		pod := podsByName[name]
+		store := stores[genTiKVAddr(tidb, name)]
+		failure, failed := previous[name]
+
+		var reason string
+		switch {
+		case store != nil && store.Store.StateName == pdapi.StoreStateDown:
+			reason = failureReasonStoreDown
+		case pod == nil || pod.DeletionTimestamp != nil:
+			// The pod is being recreated by the statefulset, the store
+			// is unknown until it is created.
+			if failed {
+				failures = append(failures, failure)
+				if failure.Replaced {
+					replacements++
+				}
+			}
+			continue
+		case !isPodReady(pod):
+			reason = failureReasonPodNotReady
+		}
+
+		if reason == "" {
+			if failed {
+				message := fmt.Sprintf("Store of pod %s is healthy again", name)
+				if failure.Replaced {
+					message += ", the replacement store is removed"
+				}
+				c.recorder.Event(tidb, v1.EventTypeNormal, TiKVStoreRecovered, message)
+			}
+			continue
+		}
+		if !failed {
+			failure = api.FailureStore{PodName: name, Since: now}
+			c.recorder.Eventf(tidb, v1.EventTypeWarning, TiKVStoreFailed,
+				"Store of pod %s is unhealthy: %s", name, reason)
+		}
+		failure.Reason = reason
+		if store != nil {
+			failure.StoreID = store.Store.ID
+		}
+		if elapsed := now.Sub(failure.Since.Time); !failure.Replaced && elapsed >= gracePeriod {
+			failure.Replaced = true
+			c.recorder.Eventf(tidb, v1.EventTypeWarning, TiKVFailover,
+				"Adding a replacement store for pod %s, it has been unhealthy for %v", name, elapsed.Round(time.Second))
+		} else if !failure.Replaced {
+			c.workqueue.AddAfter(key, gracePeriod-elapsed)
+		}
+		if failure.Replaced {
+			replacements++
+		}
+		failures = append(failures, failure)
+	}
+	tidb.Status.TiKV.FailureStores = failures
+	return replacements, nil
+}
+
+// A PD member whose pod is not ready and which PD reports unhealthy, e.g.
+// its data is lost, is recorded in the status as a failure member. Once it is
+// unhealthy beyond the failover grace period, it is deleted from PD and its
+// pod is deleted, the statefulset recreates the pod with an empty data dir and
+// it joins PD as a new member. Only one member is replaced at a time, and only
+// if the healthy members are still the majority without it.
+
+// syncPDFailover updates the failure members in the status from the pods and
+// the health of the members in PD, and replaces the member unhealthy beyond
+// the failover grace period.
+func (c *Controller) syncPDFailover(tidb *api.TiDB) error {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return err
+	}
+	spec := tidb.Spec.PDSpec
+	gracePeriod := api.DefaultPDFailoverGracePeriod
+	if spec.FailoverGracePeriod != nil {
+		gracePeriod = spec.FailoverGracePeriod.Duration
+	}
+
+	client := c.getPDClient(tidb)
+	members, err := client.GetHealth()
+	if err != nil {
+		// PD does not serve without the quorum, no member could be deleted.
+		glog.Warningf("Failed to get the health of PD of TiDB %s: %v", key, err)
+		return nil
+	}
+	healthByName := make(map[string]pdapi.MemberHealth)
+	healthy := 0
+	for _, member := range members {
+		healthByName[member.Name] = member
+		if member.Health {
+			healthy++
+		}
+	}
+	pods, err := c.podLister.Pods(tidb.Namespace).List(genSelector(tidb, componentPD))
+	if err != nil {
+		return err
+	}
+	podsByName := make(map[string]*v1.Pod)
+	for _, pod := range pods {
+		podsByName[pod.Name] = pod
+	}
+	previous := make(map[string]api.FailureMember)
+	for _, failure := range tidb.Status.PD.FailureMembers {
+		previous[failure.PodName] = failure
+	}
+
+	var failures []api.FailureMember
+	replacing := false
+	now := metav1.Now()
+	for i := 0; i < getReplicas(spec.Replicas); i++ {
+		name := genPodName(tidb, componentPD, i)
+		pod := podsByName[name]
+		member, ok := healthByName[name]
+		failure, failed := previous[name]
+
+		if failed && failure.MemberDeleted {
+			if ok && member.Health && member.MemberID != failure.MemberID {
+				c.recorder.Eventf(tidb, v1.EventTypeNormal, PDMemberRecovered,
+					"PD member %s joined again with ID %d", name, member.MemberID)
+				continue
+			}
+			// The pod is being recreated to join PD again.
+			replacing = true
+			failures = append(failures, failure)
+			continue
+		}
+		switch {
+		case !ok || member.Health || (pod != nil && pod.DeletionTimestamp == nil && isPodReady(pod)):
+			if failed {
+				c.recorder.Eventf(tidb, v1.EventTypeNormal, PDMemberRecovered,
+					"PD member %s is healthy again", name)
+			}
+			continue
+		case pod == nil || pod.DeletionTimestamp != nil:
+			// The pod is being recreated by the statefulset.
+			if failed {
+				failures = append(failures, failure)
+			}
+			continue
+		}
+		if !failed {
+			failure = api.FailureMember{PodName: name, MemberID: member.MemberID, Since: now}
+			c.recorder.Eventf(tidb, v1.EventTypeWarning, PDMemberFailed,
+				"PD member %s is unhealthy and its pod is not ready", name)
+		}
+		failures = append(failures, failure)
+	}
+	tidb.Status.PD.FailureMembers = failures
+	if replacing {
+		return nil
+	}
+
+	for i := range failures {
+		failure := &failures[i]
+		if elapsed := now.Sub(failure.Since.Time); elapsed < gracePeriod {
+			c.workqueue.AddAfter(key, gracePeriod-elapsed)
+			continue
+		}
+		// The unhealthy member is deleted, the healthy members must be the
+		// majority of the members left.
+		if !hasPDQuorumWithout(members, failure.PodName) {
+			c.recorder.Eventf(tidb, v1.EventTypeWarning, PDFailoverRefused,
+				"Refused to delete PD member %s, only %d of %d members are healthy", failure.PodName, healthy, len(members))
+			return nil
+		}
+		return c.replacePDMember(tidb, failure)
+	}
+	return nil
+}
+
+// replacePDMember deletes the failure member from PD and deletes its pod and
+// claim, the deletion of the member is recorded before the pod is deleted.
+// The member joins again with an empty data dir.
+func (c *Controller) replacePDMember(tidb *api.TiDB, failure *api.FailureMember) error {
+	c.recorder.Eventf(tidb, v1.EventTypeWarning, PDFailover,
+		"Deleting PD member %s, its pod is recreated to join PD again", failure.PodName)
+	if err := c.getPDClient(tidb).DeleteMember(failure.PodName); err != nil {
+		return fmt.Errorf("failed to delete PD member %s: %v", failure.PodName, err)
+	}
+	failure.MemberDeleted = true
+	if err := c.writeStatus(tidb); err != nil {
+		return err
+	}
+	if err := c.deletePDPodPVC(tidb, failure.PodName); err != nil {
+		return err
+	}
+	err := c.kubeclientset.CoreV1().Pods(tidb.Namespace).Delete(failure.PodName, nil)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete pod %s: %v", failure.PodName, err)
+	}
+	return nil
+}
+
+// deletePDPodPVC deletes the claim of the data volume of the PD pod. The
+// claim is kept until the pod is gone.
+func (c *Controller) deletePDPodPVC(tidb *api.TiDB, podName string) error {
+	name := fmt.Sprintf("%s-%s", pdDataVolume, podName)
+	err := c.kubeclientset.CoreV1().PersistentVolumeClaims(tidb.Namespace).Delete(name, &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete persistent volume claim %s: %v", name, err)
+	}
+	return nil
+}
+
+// hasPDQuorumWithout returns true if the healthy members are the majority of
+// the members left after the member is deleted.
+func hasPDQuorumWithout(members []pdapi.MemberHealth, name string) bool {
+	left, healthy := 0, 0
+	for _, member := range members {
+		if member.Name == name {
+			continue
+		}
+		left++
+		if member.Health {
+			healthy++
+		}
+	}
+	return healthy > left/2
+}
+
+// A TiDB server is unhealthy if its pod is not ready, i.e. the readiness
+// probe on the status port fails. It is recorded in the status as a failure
+// member, and an extra server is added by scaling out the deployment once it
+// is unhealthy beyond the failover grace period, so that the capacity for the
+// clients is kept. The extra servers are limited by the maximum failover
+// count, and they are removed once the unhealthy servers recover or are gone.
+
+// syncTiDBFailover updates the failure members of the TiDB servers in the
+// status, it returns the number of the extra servers.
+func (c *Controller) syncTiDBFailover(tidb *api.TiDB) (int, error) {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return 0, err
+	}
+	spec := tidb.Spec.TiDBSpec
+	maxFailoverCount := getTiDBMaxFailoverCount(tidb)
+	gracePeriod := api.DefaultTiDBFailoverGracePeriod
+	if spec.FailoverGracePeriod != nil {
+		gracePeriod = spec.FailoverGracePeriod.Duration
+	}
+
+	pods, err := c.podLister.Pods(tidb.Namespace).List(genSelector(tidb, componentTiDB))
+	if err != nil {
+		return 0, err
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+	previous := make(map[string]api.TiDBFailureMember)
+	for _, failure := range tidb.Status.TiDB.FailureMembers {
+		previous[failure.PodName] = failure
+	}
+
+	var failures []api.TiDBFailureMember
+	replacements := 0
+	now := metav1.Now()
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || isPodReady(pod) {
+			continue
+		}
+		failure, failed := previous[pod.Name]
+		if !failed {
+			failure = api.TiDBFailureMember{PodName: pod.Name, Since: now}
+			c.recorder.Eventf(tidb, v1.EventTypeWarning, TiDBServerFailed,
+				"TiDB server %s is unhealthy, its pod is not ready", pod.Name)
+		}
+		delete(previous, pod.Name)
+		failures = append(failures, failure)
+		if failure.Replaced {
+			replacements++
+		}
+	}
+	for name := range previous {
+		c.recorder.Eventf(tidb, v1.EventTypeNormal, TiDBServerRecovered,
+			"TiDB server %s is healthy again or deleted", name)
+	}
+
+	for i := range failures {
+		failure := &failures[i]
+		if failure.Replaced {
+			continue
+		}
+		elapsed := now.Sub(failure.Since.Time)
+		switch {
+		case elapsed < gracePeriod:
+			c.workqueue.AddAfter(key, gracePeriod-elapsed)
+		case replacements < maxFailoverCount:
+			failure.Replaced = true
+			replacements++
+			c.recorder.Eventf(tidb, v1.EventTypeWarning, TiDBFailover,
+				"Adding an extra TiDB server for %s, it has been unhealthy for %v", failure.PodName, elapsed.Round(time.Second))
+		default:
+			glog.V(4).Infof("TiDB %s has %d extra TiDB servers already, no more for %s", key, replacements, failure.PodName)
+		}
+	}
+	tidb.Status.TiDB.FailureMembers = failures
+	return getTiDBReplacements(tidb), nil
+}
+
+// getTiDBReplacements returns the number of the extra TiDB servers recorded
+// in the status, it is limited by the maximum failover count.
+func getTiDBReplacements(tidb *api.TiDB) int {
+	replacements := 0
+	for _, failure := range tidb.Status.TiDB.FailureMembers {
+		if failure.Replaced {
+			replacements++
+		}
+	}
+	if max := getTiDBMaxFailoverCount(tidb); replacements > max {
+		return max
+	}
+	return replacements
+}
+
+// getTiDBMaxFailoverCount returns the maximum number of the extra TiDB
+// servers.
+func getTiDBMaxFailoverCount(tidb *api.TiDB) int {
+	if tidb.Spec.TiDBSpec.MaxFailoverCount == nil {
+		return api.DefaultTiDBMaxFailoverCount
+	}
+	return int(*tidb.Spec.TiDBSpec.MaxFailoverCount)
+}
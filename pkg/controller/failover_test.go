@@ -0,0 +1,277 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+	"github.com/gaocegege/kubetidb/pkg/pdapi"
+)
+
+// testGracePeriod is the failover grace period of the tests, the clusters
+// are synced again after it.
+const testGracePeriod = 100 * time.Millisecond
+
+// newCreatedCluster syncs the TiDB once and returns the TiDB and the objects
+// created by the controller.
+func newCreatedCluster(t *testing.T, tidb *api.TiDB) (*api.TiDB, []runtime.Object) {
+	f := newFixture(t, tidb)
+	defer f.close()
+	f.sync(tidb)
+	return f.getTiDB(tidb), f.created()
+}
+
+// countEvents returns the number of the recorded events with the reason.
+func countEvents(f *fixture, reason string) int {
+	count := 0
+	for {
+		select {
+		case event := <-f.recorder.Events:
+			if strings.Contains(event, " "+reason+" ") {
+				count++
+			}
+		default:
+			return count
+		}
+	}
+}
+
+func TestSyncTiKVFailover(t *testing.T) {
+	tidb := newTiDB("foo")
+	tidb.Spec.TiKVSpec.FailoverGracePeriod = &metav1.Duration{Duration: testGracePeriod}
+	tidb, created := newCreatedCluster(t, tidb)
+
+	// The store of foo-tikv-0 is down in PD if down is true, its pod stays
+	// ready.
+	newCluster := func(tidb *api.TiDB, down bool) *fixture {
+		objects := append([]runtime.Object{tidb}, created...)
+		for i := 0; i < api.DefaultTiKVReplicas; i++ {
+			objects = append(objects, newComponentPod(tidb, componentTiKV, i, true))
+		}
+		f := newFixture(t, objects...)
+		f.pd.AddMember("foo-pd-0", true)
+		for i := 0; i < api.DefaultTiKVReplicas; i++ {
+			state := pdapi.StoreStateUp
+			if i == 0 && down {
+				state = pdapi.StoreStateDown
+			}
+			f.pd.AddStore(&pdapi.StoreInfo{Store: &pdapi.Store{
+				ID:        uint64(i + 1),
+				Address:   genTiKVAddr(tidb, genPodName(tidb, componentTiKV, i)),
+				StateName: state,
+			}})
+		}
+		return f
+	}
+
+	// The status of the healthy cluster is written, nothing else changes
+	// but the failure stores afterwards.
+	f := newCluster(tidb, false)
+	f.sync(tidb)
+	tidb = f.getTiDB(tidb)
+	f.close()
+
+	f = newCluster(tidb, true)
+	f.sync(tidb)
+	tidb = f.getTiDB(tidb)
+	f.close()
+	failures := tidb.Status.TiKV.FailureStores
+	if len(failures) != 1 || failures[0].PodName != "foo-tikv-0" || failures[0].Reason != failureReasonStoreDown || failures[0].Replaced {
+		t.Fatalf("Expected the down store of foo-tikv-0 to be recorded, got %+v", failures)
+	}
+	if got := countEvents(f, TiKVStoreFailed); got != 1 {
+		t.Errorf("Expected the failure to be reported once, got %d", got)
+	}
+	since := failures[0].Since
+
+	time.Sleep(2 * testGracePeriod)
+	f = newCluster(tidb, true)
+	defer f.close()
+	f.sync(tidb)
+	failures = f.getTiDB(tidb).Status.TiKV.FailureStores
+	if len(failures) != 1 || !failures[0].Since.Equal(&since) {
+		t.Fatalf("Expected the failure to be kept since %v, got %+v", since, failures)
+	}
+	if !failures[0].Replaced {
+		t.Errorf("Expected a replacement store after the grace period, got %+v", failures[0])
+	}
+	if got := countEvents(f, TiKVStoreFailed); got != 0 {
+		t.Errorf("Expected the failure not to be reported again, got %d", got)
+	}
+	if got := getReplicas(f.getStatefulSet("foo-tikv").Spec.Replicas); got != api.DefaultTiKVReplicas+1 {
+		t.Errorf("Expected the TiKV statefulset to be scaled out for the replacement, got %d", got)
+	}
+}
+
+func TestSyncTiDBFailover(t *testing.T) {
+	tidb := newTiDB("foo")
+	tidb.Spec.TiDBSpec.FailoverGracePeriod = &metav1.Duration{Duration: testGracePeriod}
+	tidb, created := newCreatedCluster(t, tidb)
+
+	newCluster := func(tidb *api.TiDB) *fixture {
+		objects := append([]runtime.Object{tidb}, created...)
+		objects = append(objects, newComponentPod(tidb, componentTiDB, 0, false))
+		return newFixture(t, objects...)
+	}
+
+	f := newCluster(tidb)
+	f.sync(tidb)
+	tidb = f.getTiDB(tidb)
+	f.close()
+	failures := tidb.Status.TiDB.FailureMembers
+	if len(failures) != 1 || failures[0].PodName != "foo-tidb-0" || failures[0].Replaced {
+		t.Fatalf("Expected the unready TiDB server to be recorded, got %+v", failures)
+	}
+	since := failures[0].Since
+
+	time.Sleep(2 * testGracePeriod)
+	f = newCluster(tidb)
+	defer f.close()
+	f.sync(tidb)
+	failures = f.getTiDB(tidb).Status.TiDB.FailureMembers
+	if len(failures) != 1 || !failures[0].Since.Equal(&since) || !failures[0].Replaced {
+		t.Fatalf("Expected the failure since %v to be replaced, got %+v", since, failures)
+	}
+	if got := countEvents(f, TiDBServerFailed); got != 0 {
+		t.Errorf("Expected the failure not to be reported again, got %d", got)
+	}
+	deployment, err := f.kubeclient.AppsV1beta2().Deployments(metav1.NamespaceDefault).Get("foo-tidb", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get the TiDB deployment: %v", err)
+	}
+	if got, expected := getReplicas(deployment.Spec.Replicas), getReplicas(tidb.Spec.TiDBSpec.Replicas)+1; got != expected {
+		t.Errorf("Expected %d TiDB servers with the extra one, got %d", expected, got)
+	}
+}
+
+func TestSyncPDFailoverRecordsFailure(t *testing.T) {
+	tidb := newTiDB("foo")
+	replicas := int32(3)
+	tidb.Spec.PDSpec.Replicas = &replicas
+	tidb.Spec.PDSpec.FailoverGracePeriod = &metav1.Duration{Duration: time.Hour}
+	tidb, created := newCreatedCluster(t, tidb)
+
+	newCluster := func(tidb *api.TiDB) *fixture {
+		objects := append([]runtime.Object{tidb}, created...)
+		for i := 0; i < 3; i++ {
+			objects = append(objects, newComponentPod(tidb, componentPD, i, i != 2))
+		}
+		f := newFixture(t, objects...)
+		for i := 0; i < 3; i++ {
+			f.pd.AddMember(genPodName(tidb, componentPD, i), i != 2)
+		}
+		return f
+	}
+
+	f := newCluster(tidb)
+	f.sync(tidb)
+	tidb = f.getTiDB(tidb)
+	f.close()
+	failures := tidb.Status.PD.FailureMembers
+	if len(failures) != 1 || failures[0].PodName != "foo-pd-2" || failures[0].MemberDeleted {
+		t.Fatalf("Expected the unhealthy member foo-pd-2 to be recorded, got %+v", failures)
+	}
+	since := failures[0].Since
+
+	f = newCluster(tidb)
+	defer f.close()
+	f.sync(tidb)
+	failures = f.getTiDB(tidb).Status.PD.FailureMembers
+	if len(failures) != 1 || !failures[0].Since.Equal(&since) {
+		t.Errorf("Expected the failure to be kept since %v, got %+v", since, failures)
+	}
+	if got := countEvents(f, PDMemberFailed); got != 0 {
+		t.Errorf("Expected the failure not to be reported again, got %d", got)
+	}
+	if len(f.pd.Members()) != 3 {
+		t.Errorf("Expected no member to be deleted within the grace period, got %v", f.pd.Members())
+	}
+}
+
+func TestSyncPDFailoverReplacesMember(t *testing.T) {
+	testCases := []struct {
+		name string
+		// failed is the PD members which are unhealthy and whose pods are
+		// not ready.
+		failed   []string
+		replaced bool
+	}{
+		{
+			name:     "quorum",
+			failed:   []string{"foo-pd-2"},
+			replaced: true,
+		},
+		{
+			name:   "no quorum",
+			failed: []string{"foo-pd-1", "foo-pd-2"},
+		},
+	}
+	for _, tc := range testCases {
+		tidb := newTiDB("foo")
+		replicas := int32(3)
+		tidb.Spec.PDSpec.Replicas = &replicas
+		tidb.Spec.PDSpec.FailoverGracePeriod = &metav1.Duration{Duration: testGracePeriod}
+		tidb, objects := newRunningCluster(t, tidb)
+		failed := make(map[string]bool)
+		for _, name := range tc.failed {
+			failed[name] = true
+		}
+		for _, obj := range objects {
+			if pod, ok := obj.(*v1.Pod); ok && failed[pod.Name] {
+				pod.Status.Conditions = nil
+			}
+		}
+		newCluster := func(tidb *api.TiDB) *fixture {
+			f := newClusterFixture(t, tidb, objects)
+			for _, name := range tc.failed {
+				f.pd.SetHealth(name, false)
+			}
+			return f
+		}
+
+		f := newCluster(tidb)
+		f.sync(tidb)
+		tidb = f.getTiDB(tidb)
+		f.close()
+		if got := len(tidb.Status.PD.FailureMembers); got != len(tc.failed) {
+			t.Fatalf("%s: expected %d failure members, got %+v", tc.name, len(tc.failed), tidb.Status.PD.FailureMembers)
+		}
+
+		time.Sleep(2 * testGracePeriod)
+		f = newCluster(tidb)
+		f.sync(tidb)
+		failures := f.getTiDB(tidb).Status.PD.FailureMembers
+		deletedPods := f.deleted("pods")
+		deletedClaims := f.deleted("persistentvolumeclaims")
+		if tc.replaced {
+			if members := f.pd.Members(); len(members) != 2 {
+				t.Errorf("%s: expected foo-pd-2 to be deleted from PD, got %v", tc.name, members)
+			}
+			if len(failures) != 1 || !failures[0].MemberDeleted {
+				t.Errorf("%s: expected the deletion of the member to be recorded, got %+v", tc.name, failures)
+			}
+			if len(deletedPods) != 1 || deletedPods[0] != "foo-pd-2" {
+				t.Errorf("%s: expected pod foo-pd-2 to be deleted, got %v", tc.name, deletedPods)
+			}
+			if len(deletedClaims) != 1 || deletedClaims[0] != pdDataVolume+"-foo-pd-2" {
+				t.Errorf("%s: expected the claim of foo-pd-2 to be deleted, got %v", tc.name, deletedClaims)
+			}
+		} else {
+			if members := f.pd.Members(); len(members) != 3 {
+				t.Errorf("%s: expected no member to be deleted without the quorum, got %v", tc.name, members)
+			}
+			if len(deletedPods) != 0 || len(deletedClaims) != 0 {
+				t.Errorf("%s: expected nothing to be deleted, got pods %v and claims %v", tc.name, deletedPods, deletedClaims)
+			}
+			if got := countEvents(f, PDFailoverRefused); got != 1 {
+				t.Errorf("%s: expected the refusal to be reported, got %d", tc.name, got)
+			}
+		}
+		f.close()
+	}
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// workerTracker tracks the keys being synced by the workers, a worker which
+// syncs a key for too long is considered wedged.
+type workerTracker struct {
+	mu      sync.Mutex
+	started bool
+	// syncing is the time each key started being synced. The workqueue
+	// never hands out a key to two workers at the same time.
+	syncing map[string]time.Time
+}
+
+func newWorkerTracker() *workerTracker {
+	return &workerTracker{syncing: make(map[string]time.Time)}
+}
+
+func (t *workerTracker) start() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.started = true
+}
+
+func (t *workerTracker) begin(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.syncing[key] = time.Now()
+}
+
+func (t *workerTracker) end(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.syncing, key)
+}
+
+// HasSynced returns true if the caches of all the informers are synced.
+func (c *Controller) HasSynced() bool {
+	for _, synced := range []func() bool{c.tidbSynced, c.podSynced, c.serviceSynced, c.configMapSynced, c.statefulSetSynced, c.deploymentSynced} {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+// WorkersStarted returns true if the workers are started, which is only the
+// case for the leader when the leader election is enabled.
+func (c *Controller) WorkersStarted() bool {
+	c.workers.mu.Lock()
+	defer c.workers.mu.Unlock()
+	return c.workers.started
+}
+
+// CheckWorkers returns an error if some worker has been syncing a cluster for
+// longer than the timeout.
+func (c *Controller) CheckWorkers(timeout time.Duration) error {
+	c.workers.mu.Lock()
+	defer c.workers.mu.Unlock()
+	now := time.Now()
+	for key, start := range c.workers.syncing {
+		if elapsed := now.Sub(start); elapsed > timeout {
+			return fmt.Errorf("worker has been syncing %s for %v", key, elapsed)
+		}
+	}
+	return nil
+}
@@ -1 +1,173 @@
 package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+// componentType is the type of the members in a TiDB cluster.
+type componentType string
+
+const (
+	componentPD   componentType = "pd"
+	componentTiKV componentType = "tikv"
+	componentTiDB componentType = "tidb"
+)
+
+const (
+	// labelCluster is the label key of the TiDB cluster name.
+	labelCluster = "kubetidb.gaocegege.com/cluster"
+	// labelComponent is the label key of the component, one of pd, tikv and tidb.
+	labelComponent = "kubetidb.gaocegege.com/component"
+
+	// annotationTolerateUnready makes the endpoints controller publish
+	// addresses of pods which are not ready yet, PD members need to resolve
+	// each other before they could be ready.
+	annotationTolerateUnready = "service.alpha.kubernetes.io/tolerate-unready-endpoints"
+)
+
+const (
+	pdClientPort   = 2379
+	pdPeerPort     = 2380
+	tikvPort       = 20160
+	tidbPort       = 4000
+	tidbStatusPort = 10080
+
+	pdDataDir   = "/var/lib/pd"
+	tikvDataDir = "/var/lib/tikv"
+)
+
+// defaultImages is used when the template of the component is not given.
+var defaultImages = map[componentType]string{
+	componentPD:   api.DefaultPDImage,
+	componentTiKV: api.DefaultTiKVImage,
+	componentTiDB: api.DefaultTiDBImage,
+}
+
+// genOwnerReference returns the controller reference of the owned resources.
+func genOwnerReference(tidb *api.TiDB) *metav1.OwnerReference {
+	boolPtr := func(b bool) *bool { return &b }
+	controllerRef := &metav1.OwnerReference{
+		APIVersion:         api.SchemeGroupVersion.String(),
+		Kind:               api.ResourceKind,
+		Name:               tidb.Name,
+		UID:                tidb.UID,
+		BlockOwnerDeletion: boolPtr(true),
+		Controller:         boolPtr(true),
+	}
+	return controllerRef
+}
+
+// genLabels returns the labels of the resources of the given component.
+func genLabels(tidb *api.TiDB, component componentType) map[string]string {
+	return map[string]string{
+		labelCluster:   tidb.Name,
+		labelComponent: string(component),
+	}
+}
+
+// genSelector returns the selector of the resources of the given component.
+func genSelector(tidb *api.TiDB, component componentType) labels.Selector {
+	return labels.SelectorFromSet(genLabels(tidb, component))
+}
+
+// genName returns the name of the resource of the component, e.g. foo-pd.
+func genName(tidb *api.TiDB, component componentType) string {
+	return fmt.Sprintf("%s-%s", tidb.Name, component)
+}
+
+// genPeerServiceName returns the name of the headless service which governs
+// the DNS names of the pods of the component.
+func genPeerServiceName(tidb *api.TiDB, component componentType) string {
+	return fmt.Sprintf("%s-peer", genName(tidb, component))
+}
+
+// genPodName returns the name of the pod with the given index, e.g. foo-pd-0.
+func genPodName(tidb *api.TiDB, component componentType, index int) string {
+	return fmt.Sprintf("%s-%d", genName(tidb, component), index)
+}
+
+// genPodFQDN returns the stable DNS name of the pod with the given index.
+func genPodFQDN(tidb *api.TiDB, component componentType, index int) string {
+	return fmt.Sprintf("%s.%s.%s.svc", genPodName(tidb, component, index),
+		genPeerServiceName(tidb, component), tidb.Namespace)
+}
+
+// genPDAddr returns the client address of PD used by TiKV and TiDB.
+func genPDAddr(tidb *api.TiDB) string {
+	return fmt.Sprintf("%s:%d", genName(tidb, componentPD), pdClientPort)
+}
+
+// getReplicas returns the replicas, the default value is 1.
+func getReplicas(replicas *int32) int {
+	if replicas == nil {
+		return 1
+	}
+	return int(*replicas)
+}
+
+// getContainer returns the container of the component in the pod spec. It
+// is the one named after the component, or the first one if there is no
+// such container.
+func getContainer(spec *v1.PodSpec, component componentType) *v1.Container {
+	if len(spec.Containers) == 0 {
+		return nil
+	}
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == string(component) {
+			return &spec.Containers[i]
+		}
+	}
+	return &spec.Containers[0]
+}
+
+// getTemplate returns a copy of the template of the component, a template
+// with the default image is generated if it is not given.
+func getTemplate(template *v1.PodTemplateSpec, component componentType) *v1.PodTemplateSpec {
+	if template != nil && len(template.Spec.Containers) != 0 {
+		return template.DeepCopy()
+	}
+	return &v1.PodTemplateSpec{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name:  string(component),
+					Image: defaultImages[component],
+				},
+			},
+		},
+	}
+}
+
+// mergeArgs returns the generated arguments followed by the arguments given
+// by users. The generated arguments are dropped if users set the same flag.
+func mergeArgs(generated, user []string) []string {
+	overridden := make(map[string]bool)
+	for _, arg := range user {
+		overridden[flagName(arg)] = true
+	}
+
+	args := make([]string, 0, len(generated)+len(user))
+	for _, arg := range generated {
+		if !overridden[flagName(arg)] {
+			args = append(args, arg)
+		}
+	}
+	return append(args, user...)
+}
+
+// flagName returns the name of the flag in the argument, e.g. --pd=foo:2379 is
+// pd.
+func flagName(arg string) string {
+	name := strings.TrimLeft(arg, "-")
+	if i := strings.Index(name, "="); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"time"
+
+	apps "k8s.io/api/apps/v1beta2"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/informers/internalinterfaces"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// The informer factory of client-go in use could not be scoped to a
+// namespace, so the informers of the owned resources are registered to it
+// below. They only watch the resources in the namespace of the controller
+// which have the cluster label, all the owned resources have it.
+
+// ownedInformerFor returns the shared informer of the owned resources of the
+// given type in the namespace, it is empty for all namespaces.
+func ownedInformerFor(factory kubeinformers.SharedInformerFactory, obj runtime.Object, namespace string) cache.SharedIndexInformer {
+	var newFunc internalinterfaces.NewInformerFunc
+	switch obj.(type) {
+	case *v1.Pod:
+		newFunc = func(client kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			return newOwnedInformer(obj, resyncPeriod,
+				func(options metav1.ListOptions) (runtime.Object, error) {
+					return client.CoreV1().Pods(namespace).List(options)
+				},
+				func(options metav1.ListOptions) (watch.Interface, error) {
+					return client.CoreV1().Pods(namespace).Watch(options)
+				})
+		}
+	case *v1.Service:
+		newFunc = func(client kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			return newOwnedInformer(obj, resyncPeriod,
+				func(options metav1.ListOptions) (runtime.Object, error) {
+					return client.CoreV1().Services(namespace).List(options)
+				},
+				func(options metav1.ListOptions) (watch.Interface, error) {
+					return client.CoreV1().Services(namespace).Watch(options)
+				})
+		}
+	case *v1.ConfigMap:
+		newFunc = func(client kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			return newOwnedInformer(obj, resyncPeriod,
+				func(options metav1.ListOptions) (runtime.Object, error) {
+					return client.CoreV1().ConfigMaps(namespace).List(options)
+				},
+				func(options metav1.ListOptions) (watch.Interface, error) {
+					return client.CoreV1().ConfigMaps(namespace).Watch(options)
+				})
+		}
+	case *apps.StatefulSet:
+		newFunc = func(client kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			return newOwnedInformer(obj, resyncPeriod,
+				func(options metav1.ListOptions) (runtime.Object, error) {
+					return client.AppsV1beta2().StatefulSets(namespace).List(options)
+				},
+				func(options metav1.ListOptions) (watch.Interface, error) {
+					return client.AppsV1beta2().StatefulSets(namespace).Watch(options)
+				})
+		}
+	case *apps.Deployment:
+		newFunc = func(client kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			return newOwnedInformer(obj, resyncPeriod,
+				func(options metav1.ListOptions) (runtime.Object, error) {
+					return client.AppsV1beta2().Deployments(namespace).List(options)
+				},
+				func(options metav1.ListOptions) (watch.Interface, error) {
+					return client.AppsV1beta2().Deployments(namespace).Watch(options)
+				})
+		}
+	default:
+		panic("unexpected owned resource type")
+	}
+	return factory.InformerFor(obj, newFunc)
+}
+
+func newOwnedInformer(obj runtime.Object, resyncPeriod time.Duration, listFunc cache.ListFunc, watchFunc cache.WatchFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = labelCluster
+				return listFunc(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = labelCluster
+				return watchFunc(options)
+			},
+		},
+		obj,
+		resyncPeriod,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+}
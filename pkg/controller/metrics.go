@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/controller"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+	listers "github.com/gaocegege/kubetidb/pkg/listers/tidb/v1beta1"
+)
+
+const metricsNamespace = "kubetidb"
+
+var (
+	syncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "sync_duration_seconds",
+		Help:      "How long syncing a TiDB cluster takes.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+	}, []string{"cluster"})
+	syncErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "sync_errors_total",
+		Help:      "Total number of failed syncs of a TiDB cluster.",
+	}, []string{"cluster"})
+
+	clustersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "clusters"),
+		"Number of the managed TiDB clusters by phase.",
+		[]string{"phase"}, nil)
+	expectationsUnsatisfiedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "expectations_unsatisfied_clusters"),
+		"Number of the TiDB clusters waiting for the expected creations and deletions, including the deletions of the pods, to be observed.",
+		nil, nil)
+)
+
+func init() {
+	prometheus.MustRegister(syncDuration, syncErrors)
+}
+
+// Collector returns the collector of the metrics of the managed clusters,
+// it is registered once by the caller.
+func (c *Controller) Collector() prometheus.Collector {
+	return &clusterCollector{
+		tidbLister:      c.tidbLister,
+		expectations:    c.expectations,
+		podExpectations: c.podExpectations,
+	}
+}
+
+// clusterCollector collects the metrics of the managed clusters from the
+// lister when it is scraped, so that they are never stale.
+type clusterCollector struct {
+	tidbLister   listers.TiDBLister
+	expectations controller.ControllerExpectationsInterface
+	// podExpectations is the deletions of the pods being scaled in or
+	// upgraded.
+	podExpectations *controller.UIDTrackingControllerExpectations
+}
+
+func (c *clusterCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- clustersDesc
+	ch <- expectationsUnsatisfiedDesc
+}
+
+func (c *clusterCollector) Collect(ch chan<- prometheus.Metric) {
+	tidbs, err := c.tidbLister.List(labels.Everything())
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+
+	phases := make(map[api.ClusterPhase]int)
+	for phase := range phaseTransitions {
+		phases[phase] = 0
+	}
+	unsatisfied := 0
+	for _, tidb := range tidbs {
+		phases[tidb.Status.Phase]++
+
+		key, err := cache.MetaNamespaceKeyFunc(tidb)
+		if err != nil {
+			continue
+		}
+		if !c.expectations.SatisfiedExpectations(key) || !c.podExpectations.SatisfiedExpectations(key) {
+			unsatisfied++
+		}
+	}
+
+	for phase, count := range phases {
+		ch <- prometheus.MustNewConstMetric(clustersDesc, prometheus.GaugeValue, float64(count), string(phase))
+	}
+	ch <- prometheus.MustNewConstMetric(expectationsUnsatisfiedDesc, prometheus.GaugeValue, float64(unsatisfied))
+}
+
+// forgetClusterMetrics deletes the metrics of the deleted cluster.
+func forgetClusterMetrics(key string) {
+	syncDuration.DeleteLabelValues(key)
+	syncErrors.DeleteLabelValues(key)
+}
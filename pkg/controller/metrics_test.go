@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+// gatherGauge returns the value of the gauge of the collector with the
+// name and the label value, or -1 if it is not collected.
+func gatherGauge(t *testing.T, collector prometheus.Collector, name, labelValue string) float64 {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather the metrics: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := metric.GetLabel()
+			if labelValue == "" && len(labels) == 0 || len(labels) == 1 && labels[0].GetValue() == labelValue {
+				return metric.GetGauge().GetValue()
+			}
+		}
+	}
+	return -1
+}
+
+func TestCollector(t *testing.T) {
+	foo := newTiDB("foo")
+	foo.Status.Phase = api.ClusterPhaseRunning
+	bar := newTiDB("bar")
+	bar.Status.Phase = api.ClusterPhaseUpgrading
+	f := newFixture(t, foo, bar)
+	defer f.close()
+	c := f.controller
+
+	if got := gatherGauge(t, c.Collector(), "kubetidb_clusters", string(api.ClusterPhaseRunning)); got != 1 {
+		t.Errorf("Expected 1 running cluster, got %v", got)
+	}
+	if got := gatherGauge(t, c.Collector(), "kubetidb_clusters", string(api.ClusterPhaseFailed)); got != 0 {
+		t.Errorf("Expected no failed cluster, got %v", got)
+	}
+	if got := gatherGauge(t, c.Collector(), "kubetidb_expectations_unsatisfied_clusters", ""); got != 0 {
+		t.Errorf("Expected no unsatisfied cluster, got %v", got)
+	}
+
+	// The clusters waiting for the owned objects or the deleted pods are
+	// both counted.
+	c.expectations.ExpectCreations(getKey(t, foo), 1)
+	if err := c.podExpectations.ExpectDeletions(getKey(t, bar), []string{"default/bar-tikv-2"}); err != nil {
+		t.Fatalf("Failed to expect the deletion: %v", err)
+	}
+	if got := gatherGauge(t, c.Collector(), "kubetidb_expectations_unsatisfied_clusters", ""); got != 2 {
+		t.Errorf("Expected 2 unsatisfied clusters, got %v", got)
+	}
+}
@@ -0,0 +1,194 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/controller"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+// The handlers below are shared by the informers of the resources owned by
+// TiDB clusters, i.e. pods, services, config maps, statefulsets and
+// deployments. Every event is resolved to the owning TiDB through the
+// controller reference, observed by the expectations and then the owner is
+// enqueued.
+
+func (c *Controller) addOwned(obj interface{}) {
+	object, ok := obj.(metav1.Object)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("expected metav1.Object but got %#v", obj))
+		return
+	}
+	if object.GetDeletionTimestamp() != nil {
+		// On a restart of the controller, it's possible an object shows up
+		// in a state that is already pending deletion. Prevent the object
+		// from being a creation observation.
+		c.deleteOwned(obj)
+		return
+	}
+
+	tidb := c.resolveControllerRef(object.GetNamespace(), metav1.GetControllerOf(object))
+	if tidb == nil {
+		// The object is not created by the controller directly, e.g. the
+		// pods of the statefulsets, there is no expectation of it.
+		if tidb = c.resolveClusterLabel(object); tidb != nil {
+			c.enqueueTiDB(tidb)
+		}
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	glog.V(4).Infof("Owned object %s/%s of %s created", object.GetNamespace(), object.GetName(), key)
+	c.expectations.CreationObserved(key)
+	c.enqueueTiDB(tidb)
+}
+
+func (c *Controller) updateOwned(old, new interface{}) {
+	newObject, ok := new.(metav1.Object)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("expected metav1.Object but got %#v", new))
+		return
+	}
+	oldObject, ok := old.(metav1.Object)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("expected metav1.Object but got %#v", old))
+		return
+	}
+	if newObject.GetResourceVersion() == oldObject.GetResourceVersion() {
+		// Periodic resync will send update events for all known objects.
+		// Two different versions of the same object will always have
+		// different RVs.
+		return
+	}
+
+	newRef := metav1.GetControllerOf(newObject)
+	oldRef := metav1.GetControllerOf(oldObject)
+	if oldRef != nil && (newRef == nil || oldRef.UID != newRef.UID) {
+		// The controller reference changed, sync the old owner.
+		if tidb := c.resolveControllerRef(oldObject.GetNamespace(), oldRef); tidb != nil {
+			c.enqueueTiDB(tidb)
+		}
+	}
+	if tidb := c.resolveControllerRef(newObject.GetNamespace(), newRef); tidb != nil {
+		c.enqueueTiDB(tidb)
+	} else if tidb := c.resolveClusterLabel(newObject); tidb != nil {
+		if newObject.GetDeletionTimestamp() != nil {
+			// The pod deleted to be upgraded is terminating.
+			c.observePodDeletion(tidb, newObject)
+		}
+		c.enqueueTiDB(tidb)
+	}
+}
+
+func (c *Controller) deleteOwned(obj interface{}) {
+	object, ok := obj.(metav1.Object)
+	if !ok {
+		// When a delete is dropped, the relist will notice an object in the
+		// store not in the list, leading to the insertion of a tombstone
+		// object which contains the deleted key/value.
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			runtime.HandleError(fmt.Errorf("couldn't get object from tombstone %#v", obj))
+			return
+		}
+		object, ok = tombstone.Obj.(metav1.Object)
+		if !ok {
+			runtime.HandleError(fmt.Errorf("tombstone contained object that is not a metav1.Object %#v", obj))
+			return
+		}
+	}
+
+	tidb := c.resolveControllerRef(object.GetNamespace(), metav1.GetControllerOf(object))
+	if tidb == nil {
+		if tidb = c.resolveClusterLabel(object); tidb != nil {
+			c.observePodDeletion(tidb, object)
+			c.enqueueTiDB(tidb)
+		}
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	glog.V(4).Infof("Owned object %s/%s of %s deleted", object.GetNamespace(), object.GetName(), key)
+	c.expectations.DeletionObserved(key)
+	c.enqueueTiDB(tidb)
+}
+
+// resolveControllerRef returns the TiDB referenced by the controller
+// reference, or nil if the reference does not point to a TiDB known by the
+// lister.
+func (c *Controller) resolveControllerRef(namespace string, controllerRef *metav1.OwnerReference) *api.TiDB {
+	if controllerRef == nil || controllerRef.Kind != api.ResourceKind {
+		return nil
+	}
+	gv, err := schema.ParseGroupVersion(controllerRef.APIVersion)
+	if err != nil || gv.Group != api.GroupName {
+		return nil
+	}
+	tidb, err := c.tidbLister.TiDBs(namespace).Get(controllerRef.Name)
+	if err != nil {
+		return nil
+	}
+	if tidb.UID != controllerRef.UID {
+		// The controller we found with this name is not the same one that
+		// the controller reference points to.
+		return nil
+	}
+	return tidb
+}
+
+// resolveClusterLabel returns the TiDB named in the cluster label of the
+// object, or nil if there is no such TiDB. It resolves the objects which are
+// owned by the TiDB indirectly, e.g. the pods of the statefulsets.
+func (c *Controller) resolveClusterLabel(object metav1.Object) *api.TiDB {
+	name, ok := object.GetLabels()[labelCluster]
+	if !ok {
+		return nil
+	}
+	tidb, err := c.tidbLister.TiDBs(object.GetNamespace()).Get(name)
+	if err != nil {
+		return nil
+	}
+	return tidb
+}
+
+// observePodDeletion observes the deletion of the pod if it is deleted by the
+// controller to be upgraded, the other objects are ignored.
+func (c *Controller) observePodDeletion(tidb *api.TiDB, object metav1.Object) {
+	pod, ok := object.(*v1.Pod)
+	if !ok {
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.podExpectations.DeletionObserved(key, controller.PodKey(pod))
+}
+
+// raiseExpectations records the creations and deletions of the owned
+// objects the informers are expected to observe. The expectations are set
+// afresh if the previous ones are fulfilled, since RaiseExpectations does
+// nothing without them and keeps their timestamp.
+func (c *Controller) raiseExpectations(key string, add, del int) {
+	if exp, exists, err := c.expectations.GetExpectations(key); err == nil && exists && !exp.Fulfilled() {
+		c.expectations.RaiseExpectations(key, add, del)
+		return
+	}
+	if err := c.expectations.SetExpectations(key, add, del); err != nil {
+		runtime.HandleError(err)
+	}
+}
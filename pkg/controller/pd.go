@@ -0,0 +1,209 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	apps "k8s.io/api/apps/v1beta2"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+const (
+	// annotationPDInitialReplicas is the annotation key of the number of PD
+	// members which bootstrap the cluster. It is recorded when the PD
+	// statefulset is created and never changes, the members beyond it join
+	// the existing cluster.
+	annotationPDInitialReplicas = "kubetidb.gaocegege.com/pd-initial-replicas"
+
+	pdDataVolume = "pd-data"
+)
+
+// defaultPDStorageSize is the size of the persistent volume of each member
+// if it is not given.
+var defaultPDStorageSize = resource.MustParse(api.DefaultPDStorageSize)
+
+// pdStartScript starts the PD member. The ordinal of the member is parsed
+// from the hostname given by the statefulset, the initial members bootstrap
+// the cluster with --initial-cluster and the others join the members before
+// them with --join. PD ignores both of them if the data dir is not empty. An
+// initial member replaced after its data is lost is not a member anymore,
+// it joins the running cluster through the client service instead.
+var pdStartScript = template.Must(template.New("pd").Parse(`set -e
+ORDINAL=${HOSTNAME##*-}
+DOMAIN=${HOSTNAME}.{{.PeerService}}.{{.Namespace}}.svc
+ARGS="--name=${HOSTNAME} \
+--data-dir={{.DataDir}} \
+--client-urls=http://0.0.0.0:{{.ClientPort}} \
+--advertise-client-urls=http://${DOMAIN}:{{.ClientPort}} \
+--peer-urls=http://0.0.0.0:{{.PeerPort}} \
+--advertise-peer-urls=http://${DOMAIN}:{{.PeerPort}}"
+if [ ${ORDINAL} -lt {{.InitialReplicas}} ]; then
+	if [ -z "$(ls -A {{.DataDir}} 2>/dev/null)" ] && \
+		MEMBERS=$(wget -q -T 5 -O - {{.ClientURL}}/pd/api/v1/members 2>/dev/null) && \
+		! echo "${MEMBERS}" | grep -q "http://${DOMAIN}:{{.PeerPort}}"; then
+		ARGS="${ARGS} --join={{.ClientURL}}"
+	else
+		ARGS="${ARGS} --initial-cluster={{.InitialCluster}}"
+	fi
+else
+	JOIN=""
+	i=0
+	while [ ${i} -lt ${ORDINAL} ]; do
+		JOIN="${JOIN}${JOIN:+,}http://{{.Name}}-${i}.{{.PeerService}}.{{.Namespace}}.svc:{{.ClientPort}}"
+		i=$((i+1))
+	done
+	ARGS="${ARGS} --join=${JOIN}"
+fi
+exec /pd-server ${ARGS} "$@"
+`))
+
+// syncPD reconciles the PD members of the cluster into a statefulset, the
+// client service and the headless peer service. The config file is rendered
+// into a config map if the config is given.
+func (c *Controller) syncPD(tidb *api.TiDB) (*apps.StatefulSet, error) {
+	services := []*v1.Service{
+		newService(tidb, componentPD, genName(tidb, componentPD), []v1.ServicePort{
+			genServicePort("client", pdClientPort),
+		}),
+		newPeerService(tidb, componentPD, []v1.ServicePort{
+			genServicePort("client", pdClientPort),
+			genServicePort("peer", pdPeerPort),
+		}),
+	}
+	if err := c.syncServices(tidb, services); err != nil {
+		return nil, err
+	}
+
+	replicas := getReplicas(tidb.Spec.PDSpec.Replicas)
+	initialReplicas := replicas
+	existing, err := c.statefulSetLister.StatefulSets(tidb.Namespace).Get(genName(tidb, componentPD))
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if existing != nil {
+		if initialReplicas, err = strconv.Atoi(existing.Annotations[annotationPDInitialReplicas]); err != nil {
+			return nil, fmt.Errorf("invalid annotation %s of statefulset %s: %v", annotationPDInitialReplicas, existing.Name, err)
+		}
+		// The members are deleted from PD before the pods are deleted.
+		if replicas, err = c.getPDReplicas(tidb, existing); err != nil {
+			return nil, err
+		}
+	}
+
+	statefulSet, err := newPDStatefulSet(tidb, replicas, initialReplicas)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && !hasVolumeClaimTemplate(existing, pdDataVolume) {
+		// The claim templates of a statefulset could not be changed, the
+		// members created before PD had persistent volumes keep the empty
+		// dirs.
+		statefulSet.Spec.VolumeClaimTemplates = nil
+		statefulSet.Spec.Template.Spec.Volumes = append(statefulSet.Spec.Template.Spec.Volumes, v1.Volume{
+			Name:         pdDataVolume,
+			VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+		})
+	}
+	if err := c.syncConfig(tidb, componentPD, &statefulSet.Spec.Template); err != nil {
+		return nil, err
+	}
+	return c.syncStatefulSet(tidb, statefulSet)
+}
+
+// newPDStatefulSet returns the statefulset of PD, the first initialReplicas
+// members bootstrap the cluster. Every member has its own persistent volume.
+func newPDStatefulSet(tidb *api.TiDB, replicas, initialReplicas int) (*apps.StatefulSet, error) {
+	template := newPodTemplate(tidb, componentPD)
+	script, err := genPDStartScript(tidb, initialReplicas)
+	if err != nil {
+		return nil, err
+	}
+
+	container := getContainer(&template.Spec, componentPD)
+	// The arguments given by users are passed to the script, and they
+	// override the generated ones.
+	container.Command = []string{"/bin/sh", "-c", script, "pd-server"}
+	container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+		Name:      pdDataVolume,
+		MountPath: pdDataDir,
+	})
+
+	statefulSet := newStatefulSet(tidb, componentPD, replicas, template)
+	statefulSet.Spec.VolumeClaimTemplates = []v1.PersistentVolumeClaim{newPDDataClaim(tidb)}
+	statefulSet.Annotations = map[string]string{
+		annotationPDInitialReplicas: strconv.Itoa(initialReplicas),
+	}
+	return statefulSet, nil
+}
+
+// newPDDataClaim returns the claim template of the data volume of the
+// members. The claims are labelled with the cluster so they could be found
+// when the cluster is torn down.
+func newPDDataClaim(tidb *api.TiDB) v1.PersistentVolumeClaim {
+	spec := tidb.Spec.PDSpec
+	size := defaultPDStorageSize
+	if spec.StorageSize != nil {
+		size = *spec.StorageSize
+	}
+
+	return v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   pdDataVolume,
+			Labels: genLabels(tidb, componentPD),
+		},
+		Spec: v1.PersistentVolumeClaimSpec{
+			AccessModes:      []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+			StorageClassName: spec.StorageClassName,
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceStorage: size},
+			},
+		},
+	}
+}
+
+// hasVolumeClaimTemplate returns true if the statefulset has the claim
+// template.
+func hasVolumeClaimTemplate(statefulSet *apps.StatefulSet, name string) bool {
+	for _, claim := range statefulSet.Spec.VolumeClaimTemplates {
+		if claim.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// genPDStartScript returns the start script of the PD members.
+func genPDStartScript(tidb *api.TiDB, initialReplicas int) (string, error) {
+	var buf bytes.Buffer
+	err := pdStartScript.Execute(&buf, map[string]interface{}{
+		"Name":            genName(tidb, componentPD),
+		"Namespace":       tidb.Namespace,
+		"PeerService":     genPeerServiceName(tidb, componentPD),
+		"DataDir":         pdDataDir,
+		"ClientPort":      pdClientPort,
+		"ClientURL":       genPDURL(tidb),
+		"PeerPort":        pdPeerPort,
+		"InitialReplicas": initialReplicas,
+		"InitialCluster":  genPDInitialCluster(tidb, initialReplicas),
+	})
+	return buf.String(), err
+}
+
+// genPDInitialCluster returns the initial cluster of PD, e.g.
+// foo-pd-0=http://foo-pd-0.foo-pd-peer.default.svc:2380.
+func genPDInitialCluster(tidb *api.TiDB, replicas int) string {
+	members := make([]string, 0, replicas)
+	for i := 0; i < replicas; i++ {
+		members = append(members, fmt.Sprintf("%s=http://%s:%d",
+			genPodName(tidb, componentPD, i), genPodFQDN(tidb, componentPD, i), pdPeerPort))
+	}
+	return strings.Join(members, ",")
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"fmt"
+
+	"k8s.io/api/core/v1"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+	"github.com/gaocegege/kubetidb/pkg/pdapi"
+)
+
+// newPDClient returns the client of the PD API at the URL.
+func newPDClient(url string) pdapi.Client {
+	return pdapi.NewClient(url, pdapi.DefaultTimeout)
+}
+
+// genPDURL returns the URL of the PD client service, it is resolved in the
+// cluster.
+func genPDURL(tidb *api.TiDB) string {
+	return fmt.Sprintf("http://%s.%s.svc:%d", genName(tidb, componentPD), tidb.Namespace, pdClientPort)
+}
+
+// getPDClient returns the client of the PD API of the cluster.
+func (c *Controller) getPDClient(tidb *api.TiDB) pdapi.Client {
+	return c.pdClientFor(genPDURL(tidb))
+}
+
+// checkPDHealth returns an error unless every PD pod is a healthy member.
+func (c *Controller) checkPDHealth(tidb *api.TiDB, pods []*v1.Pod) error {
+	members, err := c.getPDClient(tidb).GetHealth()
+	if err != nil {
+		return fmt.Errorf("failed to get the health of PD: %v", err)
+	}
+	healthy := make(map[string]bool)
+	for _, member := range members {
+		healthy[member.Name] = member.Health
+	}
+	for _, pod := range pods {
+		if !healthy[pod.Name] {
+			return fmt.Errorf("PD member %s is not healthy", pod.Name)
+		}
+	}
+	return nil
+}
+
+// checkTiKVHealth returns an error unless the store of every TiKV pod is up.
+func (c *Controller) checkTiKVHealth(tidb *api.TiDB, pods []*v1.Pod) error {
+	info, err := c.getPDClient(tidb).GetStores()
+	if err != nil {
+		return fmt.Errorf("failed to get the stores from PD: %v", err)
+	}
+	states := make(map[string]string)
+	for _, store := range info.Stores {
+		if store.Store != nil {
+			states[store.Store.Address] = store.Store.StateName
+		}
+	}
+	for _, pod := range pods {
+		if state := states[genTiKVAddr(tidb, pod.Name)]; state != pdapi.StoreStateUp {
+			return fmt.Errorf("store of TiKV %s is %q, not %s", pod.Name, state, pdapi.StoreStateUp)
+		}
+	}
+	return nil
+}
+
+// genTiKVAddr returns the address advertised by the TiKV store in the pod.
+func genTiKVAddr(tidb *api.TiDB, podName string) string {
+	return fmt.Sprintf("%s.%s.%s.svc:%d", podName, genPeerServiceName(tidb, componentTiKV), tidb.Namespace, tikvPort)
+}
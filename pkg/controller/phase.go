@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"k8s.io/api/core/v1"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+// clusterObservation is what the controller observes from the owned pods,
+// it drives the transitions of the cluster phase.
+type clusterObservation struct {
+	// deleting is true if the cluster is being deleted.
+	deleting bool
+	// desired is the sum of the desired replicas of all the components.
+	desired int
+	// existing is the number of the owned pods which are not terminating.
+	existing int
+	// ready is the number of the ready pods.
+	ready int
+	// failed is the number of the failed pods.
+	failed int
+	// scaling is true if the pods of some component do not match the
+	// desired replicas.
+	scaling bool
+	// upgrading is true if some pod does not match the template.
+	upgrading bool
+}
+
+// phaseTransitions is the state machine of the cluster lifecycle, it lists
+// the phases which could be transited to from the phase.
+var phaseTransitions = map[api.ClusterPhase][]api.ClusterPhase{
+	// The first status could be written after the pods exist, e.g. for the
+	// legacy clusters whose phase was Unknown.
+	api.ClusterPhaseNone: {
+		api.ClusterPhaseCreating, api.ClusterPhaseBootstrapping, api.ClusterPhaseRunning,
+		api.ClusterPhaseFailed, api.ClusterPhaseDeleting,
+	},
+	api.ClusterPhaseCreating: {
+		api.ClusterPhaseBootstrapping, api.ClusterPhaseRunning,
+		api.ClusterPhaseFailed, api.ClusterPhaseDeleting,
+	},
+	api.ClusterPhaseBootstrapping: {
+		api.ClusterPhaseRunning, api.ClusterPhaseFailed, api.ClusterPhaseDeleting,
+	},
+	api.ClusterPhaseRunning: {
+		api.ClusterPhaseScaling, api.ClusterPhaseUpgrading, api.ClusterPhaseDegraded,
+		api.ClusterPhaseFailed, api.ClusterPhaseDeleting,
+	},
+	api.ClusterPhaseScaling: {
+		api.ClusterPhaseRunning, api.ClusterPhaseUpgrading, api.ClusterPhaseDegraded,
+		api.ClusterPhaseFailed, api.ClusterPhaseDeleting,
+	},
+	api.ClusterPhaseUpgrading: {
+		api.ClusterPhaseRunning, api.ClusterPhaseScaling, api.ClusterPhaseDegraded,
+		api.ClusterPhaseFailed, api.ClusterPhaseDeleting,
+	},
+	api.ClusterPhaseDegraded: {
+		api.ClusterPhaseRunning, api.ClusterPhaseScaling, api.ClusterPhaseUpgrading,
+		api.ClusterPhaseFailed, api.ClusterPhaseDeleting,
+	},
+	api.ClusterPhaseFailed: {
+		api.ClusterPhaseRunning, api.ClusterPhaseScaling, api.ClusterPhaseUpgrading,
+		api.ClusterPhaseDegraded, api.ClusterPhaseDeleting,
+	},
+	api.ClusterPhaseDeleting: {},
+}
+
+// canTransit returns true if the phase could be transited to the next one.
+func canTransit(phase, next api.ClusterPhase) bool {
+	for _, p := range phaseTransitions[phase] {
+		if p == next {
+			return true
+		}
+	}
+	return false
+}
+
+// nextPhase returns the phase the cluster should be in according to the
+// observation. The current phase is kept if the transition is not allowed.
+func nextPhase(phase api.ClusterPhase, observation *clusterObservation) api.ClusterPhase {
+	next := desiredPhase(phase, observation)
+	if next == phase || !canTransit(phase, next) {
+		return phase
+	}
+	return next
+}
+
+// desiredPhase returns the phase matching the observation. A cluster which
+// has never been running is created and bootstrapped first, the others are
+// scaled, upgraded or degraded.
+func desiredPhase(phase api.ClusterPhase, observation *clusterObservation) api.ClusterPhase {
+	allReady := observation.ready >= observation.desired
+
+	switch {
+	case observation.deleting:
+		return api.ClusterPhaseDeleting
+	case observation.failed > 0:
+		return api.ClusterPhaseFailed
+	}
+
+	switch phase {
+	case api.ClusterPhaseNone, api.ClusterPhaseCreating:
+		if observation.existing < observation.desired {
+			return api.ClusterPhaseCreating
+		}
+		if !allReady {
+			return api.ClusterPhaseBootstrapping
+		}
+		return api.ClusterPhaseRunning
+	case api.ClusterPhaseBootstrapping:
+		if !allReady {
+			return api.ClusterPhaseBootstrapping
+		}
+		return api.ClusterPhaseRunning
+	}
+
+	switch {
+	case observation.scaling:
+		return api.ClusterPhaseScaling
+	case observation.upgrading:
+		return api.ClusterPhaseUpgrading
+	case !allReady:
+		return api.ClusterPhaseDegraded
+	}
+	return api.ClusterPhaseRunning
+}
+
+// isAvailablePhase returns true if the cluster serves requests in the phase.
+func isAvailablePhase(phase api.ClusterPhase) bool {
+	switch phase {
+	case api.ClusterPhaseRunning, api.ClusterPhaseScaling, api.ClusterPhaseUpgrading:
+		return true
+	}
+	return false
+}
+
+// phaseEventType returns the type of the Event recorded when the cluster
+// transits to the phase.
+func phaseEventType(phase api.ClusterPhase) string {
+	switch phase {
+	case api.ClusterPhaseDegraded, api.ClusterPhaseFailed:
+		return v1.EventTypeWarning
+	}
+	return v1.EventTypeNormal
+}
@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+func TestNextPhase(t *testing.T) {
+	testCases := []struct {
+		name        string
+		phase       api.ClusterPhase
+		observation clusterObservation
+		expected    api.ClusterPhase
+	}{
+		{
+			name:        "none without pods",
+			phase:       api.ClusterPhaseNone,
+			observation: clusterObservation{desired: 5},
+			expected:    api.ClusterPhaseCreating,
+		},
+		{
+			name:        "none with the pods not ready",
+			phase:       api.ClusterPhaseNone,
+			observation: clusterObservation{desired: 5, existing: 5, ready: 3},
+			expected:    api.ClusterPhaseBootstrapping,
+		},
+		{
+			name:        "none with the pods ready",
+			phase:       api.ClusterPhaseNone,
+			observation: clusterObservation{desired: 5, existing: 5, ready: 5},
+			expected:    api.ClusterPhaseRunning,
+		},
+		{
+			name:        "none with a failed pod",
+			phase:       api.ClusterPhaseNone,
+			observation: clusterObservation{desired: 5, existing: 5, ready: 4, failed: 1},
+			expected:    api.ClusterPhaseFailed,
+		},
+		{
+			name:        "none being deleted",
+			phase:       api.ClusterPhaseNone,
+			observation: clusterObservation{deleting: true},
+			expected:    api.ClusterPhaseDeleting,
+		},
+		{
+			name:        "creating to bootstrapping",
+			phase:       api.ClusterPhaseCreating,
+			observation: clusterObservation{desired: 5, existing: 5},
+			expected:    api.ClusterPhaseBootstrapping,
+		},
+		{
+			name:        "bootstrapping to running",
+			phase:       api.ClusterPhaseBootstrapping,
+			observation: clusterObservation{desired: 5, existing: 5, ready: 5},
+			expected:    api.ClusterPhaseRunning,
+		},
+		{
+			name:        "running to scaling",
+			phase:       api.ClusterPhaseRunning,
+			observation: clusterObservation{desired: 6, existing: 5, ready: 5, scaling: true},
+			expected:    api.ClusterPhaseScaling,
+		},
+		{
+			name:        "running to upgrading",
+			phase:       api.ClusterPhaseRunning,
+			observation: clusterObservation{desired: 5, existing: 5, ready: 5, upgrading: true},
+			expected:    api.ClusterPhaseUpgrading,
+		},
+		{
+			name:        "running to degraded",
+			phase:       api.ClusterPhaseRunning,
+			observation: clusterObservation{desired: 5, existing: 5, ready: 4},
+			expected:    api.ClusterPhaseDegraded,
+		},
+		{
+			name:        "failed to running",
+			phase:       api.ClusterPhaseFailed,
+			observation: clusterObservation{desired: 5, existing: 5, ready: 5},
+			expected:    api.ClusterPhaseRunning,
+		},
+		{
+			name:        "failed to scaling",
+			phase:       api.ClusterPhaseFailed,
+			observation: clusterObservation{desired: 5, existing: 4, ready: 4, scaling: true},
+			expected:    api.ClusterPhaseScaling,
+		},
+		{
+			name:        "running is not created again",
+			phase:       api.ClusterPhaseRunning,
+			observation: clusterObservation{desired: 5, existing: 4, ready: 4},
+			expected:    api.ClusterPhaseDegraded,
+		},
+		{
+			name:        "deleting is final",
+			phase:       api.ClusterPhaseDeleting,
+			observation: clusterObservation{desired: 5, existing: 5, ready: 5},
+			expected:    api.ClusterPhaseDeleting,
+		},
+	}
+	for _, tc := range testCases {
+		observation := tc.observation
+		if got := nextPhase(tc.phase, &observation); got != tc.expected {
+			t.Errorf("%s: expected phase %q, got %q", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestPhaseTransitions(t *testing.T) {
+	// Every phase desired from a phase must be a transition of it, or the
+	// cluster would be stuck in the phase. Deleting is final, the cluster is
+	// not undeleted.
+	observations := []clusterObservation{
+		{desired: 5},
+		{desired: 5, existing: 5},
+		{desired: 5, existing: 5, ready: 5},
+		{desired: 5, existing: 5, ready: 4, failed: 1},
+		{desired: 5, existing: 4, ready: 4, scaling: true},
+		{desired: 5, existing: 5, ready: 5, upgrading: true},
+		{deleting: true},
+	}
+	for phase := range phaseTransitions {
+		if phase == api.ClusterPhaseDeleting {
+			continue
+		}
+		for i := range observations {
+			next := desiredPhase(phase, &observations[i])
+			if next != phase && !canTransit(phase, next) {
+				t.Errorf("Expected phase %q to transit to %q for %+v", phase, next, observations[i])
+			}
+		}
+	}
+}
+
+func TestSyncPhaseOfRunningCluster(t *testing.T) {
+	// The first status of a cluster whose pods are ready, e.g. a legacy
+	// cluster whose phase was Unknown, is Running.
+	tidb, created := newCreatedCluster(t, newTiDB("foo"))
+	tidb.Status = api.ClusterStatus{}
+	objects := append([]runtime.Object{tidb}, created...)
+	objects = append(objects, newComponentPod(tidb, componentPD, 0, true), newComponentPod(tidb, componentTiDB, 0, true))
+	for i := 0; i < api.DefaultTiKVReplicas; i++ {
+		objects = append(objects, newComponentPod(tidb, componentTiKV, i, true))
+	}
+	f := newFixture(t, objects...)
+	defer f.close()
+	f.pd.AddMember("foo-pd-0", true)
+	f.sync(tidb)
+
+	if phase := f.getTiDB(tidb).Status.Phase; phase != api.ClusterPhaseRunning {
+		t.Errorf("Expected phase %q, got %q", api.ClusterPhaseRunning, phase)
+	}
+	if got := countEvents(f, string(api.ClusterPhaseRunning)); got != 1 {
+		t.Errorf("Expected the transition to be recorded once, got %d", got)
+	}
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"k8s.io/api/core/v1"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+// newPodTemplate returns the pod template of the component, the labels of
+// the component are added to the one given by users.
+func newPodTemplate(tidb *api.TiDB, component componentType) *v1.PodTemplateSpec {
+	template := getTemplate(getComponentTemplate(tidb, component), component)
+
+	podLabels := make(map[string]string)
+	for k, v := range template.Labels {
+		podLabels[k] = v
+	}
+	for k, v := range genLabels(tidb, component) {
+		podLabels[k] = v
+	}
+	template.Labels = podLabels
+
+	container := getContainer(&template.Spec, component)
+	if len(container.Ports) == 0 {
+		container.Ports = genContainerPorts(component)
+	}
+	return template
+}
+
+// genContainerPorts returns the default ports of the component.
+func genContainerPorts(component componentType) []v1.ContainerPort {
+	switch component {
+	case componentPD:
+		return []v1.ContainerPort{
+			{Name: "client", ContainerPort: pdClientPort},
+			{Name: "peer", ContainerPort: pdPeerPort},
+		}
+	case componentTiKV:
+		return []v1.ContainerPort{
+			{Name: "server", ContainerPort: tikvPort},
+		}
+	case componentTiDB:
+		return []v1.ContainerPort{
+			{Name: "mysql", ContainerPort: tidbPort},
+			{Name: "status", ContainerPort: tidbStatusPort},
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/api/core/v1"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+	"github.com/gaocegege/kubetidb/pkg/tidbapi"
+)
+
+const (
+	// annotationHotConfigHash is the annotation key of the hash of the
+	// values of the hot-reloadable keys which the running component has
+	// applied, it is kept in the config map.
+	annotationHotConfigHash = "kubetidb.gaocegege.com/hot-config-hash"
+
+	// ConfigReloaded is used as part of the Event 'reason' when the
+	// hot-reloadable config of a component is applied online
+	ConfigReloaded = "ConfigReloaded"
+	// ConfigReloadFailed is used as part of the Event 'reason' when the
+	// hot-reloadable config of a component fails to be applied online
+	ConfigReloadFailed = "ConfigReloadFailed"
+)
+
+// hotReloadableKeys is the keys of the typed config which the components
+// change online through their APIs, the new values of them are applied
+// without restarting the pods. TiKV could not change its config online, and
+// so could not the raw config, they take effect after the pods restart.
+var hotReloadableKeys = map[componentType]map[string]bool{
+	componentPD: {
+		"log.level":                       true,
+		"schedule.max-store-down-time":    true,
+		"schedule.leader-schedule-limit":  true,
+		"schedule.region-schedule-limit":  true,
+		"schedule.replica-schedule-limit": true,
+		"replication.max-replicas":        true,
+		"replication.location-labels":     true,
+	},
+	componentTiDB: {
+		"log.level": true,
+	},
+}
+
+// isHotReloadable returns true if the key of the config of the component is
+// changed online.
+func isHotReloadable(component componentType, key string) bool {
+	return hotReloadableKeys[component][key]
+}
+
+// classifyConfig splits the keys of the config into the hot-reloadable ones
+// and the ones which need the pods restarted.
+func classifyConfig(component componentType, keys map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	hot := make(map[string]interface{})
+	restart := make(map[string]interface{})
+	for key, value := range keys {
+		if isHotReloadable(component, key) {
+			hot[key] = value
+		} else {
+			restart[key] = value
+		}
+	}
+	return hot, restart
+}
+
+// sortedKeys returns the keys of the config in order.
+func sortedKeys(keys map[string]interface{}) []string {
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// newTiDBClient returns the client of the API of the TiDB server at the URL.
+func newTiDBClient(url string) tidbapi.Client {
+	return tidbapi.NewClient(url, tidbapi.DefaultTimeout)
+}
+
+// reloadConfig applies the values of the hot-reloadable keys to the running
+// component. The pods which are not ready are skipped, they load the values
+// from the config file when they start.
+func (c *Controller) reloadConfig(tidb *api.TiDB, component componentType, hot map[string]interface{}) error {
+	switch component {
+	case componentPD:
+		return c.reloadPDConfig(tidb, hot)
+	case componentTiDB:
+		return c.reloadTiDBConfig(tidb, hot)
+	}
+	return nil
+}
+
+// reloadPDConfig applies the schedule and the replication config through
+// the PD cluster, and the log level to every member.
+func (c *Controller) reloadPDConfig(tidb *api.TiDB, hot map[string]interface{}) error {
+	schedule := make(map[string]interface{})
+	replication := make(map[string]interface{})
+	for key, value := range hot {
+		switch {
+		case strings.HasPrefix(key, "schedule."):
+			schedule[strings.TrimPrefix(key, "schedule.")] = value
+		case strings.HasPrefix(key, "replication."):
+			if labels, ok := value.([]string); ok {
+				// PD takes the location labels separated by commas.
+				value = strings.Join(labels, ",")
+			}
+			replication[strings.TrimPrefix(key, "replication.")] = value
+		}
+	}
+
+	client := c.getPDClient(tidb)
+	if len(schedule) != 0 {
+		if err := client.SetScheduleConfig(schedule); err != nil {
+			return fmt.Errorf("failed to set the schedule config of PD: %v", err)
+		}
+	}
+	if len(replication) != 0 {
+		if err := client.SetReplicationConfig(replication); err != nil {
+			return fmt.Errorf("failed to set the replication config of PD: %v", err)
+		}
+	}
+
+	level, ok := hot["log.level"].(string)
+	if !ok {
+		return nil
+	}
+	pods, err := c.getReadyPods(tidb, componentPD)
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods {
+		// The log level is only set on the member serving the request.
+		url := fmt.Sprintf("http://%s.%s.%s.svc:%d", pod.Name, genPeerServiceName(tidb, componentPD), tidb.Namespace, pdClientPort)
+		if err := c.pdClientFor(url).SetLogLevel(level); err != nil {
+			return fmt.Errorf("failed to set the log level of PD %s: %v", pod.Name, err)
+		}
+	}
+	return nil
+}
+
+// reloadTiDBConfig applies the log level to every TiDB server through the
+// status port.
+func (c *Controller) reloadTiDBConfig(tidb *api.TiDB, hot map[string]interface{}) error {
+	level, ok := hot["log.level"].(string)
+	if !ok {
+		return nil
+	}
+	pods, err := c.getReadyPods(tidb, componentTiDB)
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods {
+		if pod.Status.PodIP == "" {
+			continue
+		}
+		url := fmt.Sprintf("http://%s:%d", pod.Status.PodIP, tidbStatusPort)
+		if err := c.tidbClientFor(url).SetLogLevel(level); err != nil {
+			return fmt.Errorf("failed to set the log level of TiDB %s: %v", pod.Name, err)
+		}
+	}
+	return nil
+}
+
+// getReadyPods returns the ready pods of the component which are not being
+// deleted.
+func (c *Controller) getReadyPods(tidb *api.TiDB, component componentType) ([]*v1.Pod, error) {
+	pods, err := c.podLister.Pods(tidb.Namespace).List(genSelector(tidb, component))
+	if err != nil {
+		return nil, err
+	}
+	var ready []*v1.Pod
+	for _, pod := range pods {
+		if pod.DeletionTimestamp == nil && isPodReady(pod) {
+			ready = append(ready, pod)
+		}
+	}
+	return ready, nil
+}
@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+	"github.com/gaocegege/kubetidb/pkg/pdapi"
+	"github.com/gaocegege/kubetidb/pkg/tidbapi"
+)
+
+// newComponentPod returns the pod of the component with the ordinal, it is
+// running and ready if ready is true.
+func newComponentPod(tidb *api.TiDB, component componentType, ordinal int, ready bool) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      genPodName(tidb, component, ordinal),
+			Namespace: tidb.Namespace,
+			Labels:    genLabels(tidb, component),
+		},
+		Status: v1.PodStatus{Phase: v1.PodPending},
+	}
+	if ready {
+		pod.Status.Phase = v1.PodRunning
+		pod.Status.PodIP = "10.0.0.1"
+		pod.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+	}
+	return pod
+}
+
+func TestClassifyConfig(t *testing.T) {
+	keys := map[string]interface{}{
+		"log.level":                      "debug",
+		"schedule.leader-schedule-limit": int32(4),
+		"token-limit":                    int32(1000),
+	}
+	testCases := []struct {
+		component componentType
+		hot       map[string]interface{}
+		restart   map[string]interface{}
+	}{
+		{
+			component: componentPD,
+			hot:       map[string]interface{}{"log.level": "debug", "schedule.leader-schedule-limit": int32(4)},
+			restart:   map[string]interface{}{"token-limit": int32(1000)},
+		},
+		{
+			component: componentTiDB,
+			hot:       map[string]interface{}{"log.level": "debug"},
+			restart:   map[string]interface{}{"schedule.leader-schedule-limit": int32(4), "token-limit": int32(1000)},
+		},
+		{
+			component: componentTiKV,
+			hot:       map[string]interface{}{},
+			restart:   keys,
+		},
+	}
+	for _, tc := range testCases {
+		hot, restart := classifyConfig(tc.component, keys)
+		if !reflect.DeepEqual(hot, tc.hot) {
+			t.Errorf("%s: expected hot keys %v, got %v", tc.component, tc.hot, hot)
+		}
+		if !reflect.DeepEqual(restart, tc.restart) {
+			t.Errorf("%s: expected restart keys %v, got %v", tc.component, tc.restart, restart)
+		}
+	}
+}
+
+func TestRenderConfigHashes(t *testing.T) {
+	limit := int32(4)
+	newPDConfig := func() *api.PDConfig {
+		return &api.PDConfig{
+			Log:      &api.PDLogConfig{Level: "info"},
+			Schedule: &api.PDScheduleConfig{LeaderScheduleLimit: &limit},
+			Raw:      "[metric]\ninterval = \"15s\"\n",
+		}
+	}
+	render := func(config *api.PDConfig) *componentConfig {
+		tidb := newTiDB("foo")
+		tidb.Spec.PDSpec.Config = config
+		rendered, err := renderConfig(tidb, componentPD)
+		if err != nil {
+			t.Fatalf("Failed to render the config: %v", err)
+		}
+		return rendered
+	}
+	original := render(newPDConfig())
+
+	testCases := []struct {
+		name           string
+		mutate         func(config *api.PDConfig)
+		hotChanged     bool
+		restartChanged bool
+	}{
+		{
+			name:   "nothing changed",
+			mutate: func(config *api.PDConfig) {},
+		},
+		{
+			name:       "change a hot key",
+			mutate:     func(config *api.PDConfig) { config.Log.Level = "debug" },
+			hotChanged: true,
+		},
+		{
+			name: "set a hot key",
+			mutate: func(config *api.PDConfig) {
+				config.Replication = &api.PDReplicationConfig{LocationLabels: []string{"zone", "host"}}
+			},
+			hotChanged:     true,
+			restartChanged: true,
+		},
+		{
+			name:           "unset a hot key",
+			mutate:         func(config *api.PDConfig) { config.Log = nil },
+			hotChanged:     true,
+			restartChanged: true,
+		},
+		{
+			name:           "change the raw config",
+			mutate:         func(config *api.PDConfig) { config.Raw = "[metric]\ninterval = \"30s\"\n" },
+			restartChanged: true,
+		},
+	}
+	for _, tc := range testCases {
+		config := newPDConfig()
+		tc.mutate(config)
+		rendered := render(config)
+		if changed := rendered.hotHash != original.hotHash; changed != tc.hotChanged {
+			t.Errorf("%s: expected the hot hash changed %v, got %v", tc.name, tc.hotChanged, changed)
+		}
+		if changed := rendered.restartHash != original.restartHash; changed != tc.restartChanged {
+			t.Errorf("%s: expected the restart hash changed %v, got %v", tc.name, tc.restartChanged, changed)
+		}
+	}
+}
+
+func TestReloadPDConfig(t *testing.T) {
+	tidb := newTiDB("foo")
+	limit, replicas := int32(4), int32(5)
+	tidb.Spec.PDSpec.Config = &api.PDConfig{
+		Log:         &api.PDLogConfig{Level: "debug"},
+		Schedule:    &api.PDScheduleConfig{LeaderScheduleLimit: &limit},
+		Replication: &api.PDReplicationConfig{MaxReplicas: &replicas, LocationLabels: []string{"zone", "host"}},
+	}
+	f := newFixture(t, tidb,
+		newComponentPod(tidb, componentPD, 0, true),
+		newComponentPod(tidb, componentPD, 1, true),
+		newComponentPod(tidb, componentPD, 2, false))
+	defer f.close()
+	var urls []string
+	f.controller.pdClientFor = func(url string) pdapi.Client {
+		urls = append(urls, url)
+		return pdapi.NewClient(f.pd.URL, pdapi.DefaultTimeout)
+	}
+
+	config, err := renderConfig(tidb, componentPD)
+	if err != nil {
+		t.Fatalf("Failed to render the config: %v", err)
+	}
+	if err := f.controller.reloadConfig(tidb, componentPD, config.hot); err != nil {
+		t.Fatalf("Failed to reload the config: %v", err)
+	}
+
+	if got := f.pd.LocationLabels(); got != "zone,host" {
+		t.Errorf("Expected the location labels zone,host, got %q", got)
+	}
+	if got := f.pd.ScheduleConfig()["leader-schedule-limit"]; got != float64(limit) {
+		t.Errorf("Expected the leader schedule limit %d, got %v", limit, got)
+	}
+	if got := f.pd.LogLevel(); got != "debug" {
+		t.Errorf("Expected the log level debug, got %s", got)
+	}
+	// The cluster client and the ready members are requested.
+	sort.Strings(urls)
+	expected := []string{
+		"http://foo-pd-0.foo-pd-peer.default.svc:2379",
+		"http://foo-pd-1.foo-pd-peer.default.svc:2379",
+		genPDURL(tidb),
+	}
+	sort.Strings(expected)
+	if !reflect.DeepEqual(urls, expected) {
+		t.Errorf("Expected the PD clients %v, got %v", expected, urls)
+	}
+}
+
+func TestReloadTiDBConfig(t *testing.T) {
+	tidb := newTiDB("foo")
+	f := newFixture(t, tidb,
+		newComponentPod(tidb, componentTiDB, 0, true),
+		newComponentPod(tidb, componentTiDB, 1, false))
+	defer f.close()
+	var urls []string
+	f.controller.tidbClientFor = func(url string) tidbapi.Client {
+		urls = append(urls, url)
+		return tidbapi.NewClient(f.tidb.URL, tidbapi.DefaultTimeout)
+	}
+
+	if err := f.controller.reloadConfig(tidb, componentTiDB, map[string]interface{}{"log.level": "warn"}); err != nil {
+		t.Fatalf("Failed to reload the config: %v", err)
+	}
+	if got := f.tidb.LogLevel(); got != "warn" {
+		t.Errorf("Expected the log level warn, got %s", got)
+	}
+	if expected := []string{"http://10.0.0.1:10080"}; !reflect.DeepEqual(urls, expected) {
+		t.Errorf("Expected the TiDB clients %v, got %v", expected, urls)
+	}
+
+	// An invalid level is refused by TiDB.
+	if err := f.controller.reloadConfig(tidb, componentTiDB, map[string]interface{}{"log.level": "verbose"}); err == nil {
+		t.Errorf("Expected an error reloading an invalid log level")
+	}
+	if got := f.tidb.LogLevel(); got != "warn" {
+		t.Errorf("Expected the log level to stay warn, got %s", got)
+	}
+}
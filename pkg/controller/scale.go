@@ -0,0 +1,344 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	apps "k8s.io/api/apps/v1beta2"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+	"github.com/gaocegege/kubetidb/pkg/pdapi"
+)
+
+const (
+	// ScalingInTiKV is used as part of the Event 'reason' when a TiKV store
+	// is marked offline to be removed.
+	ScalingInTiKV = "ScalingInTiKV"
+	// ScaledInTiKV is used as part of the Event 'reason' when a TiKV store
+	// is tombstone and its pod is deleted.
+	ScaledInTiKV = "ScaledInTiKV"
+	// ScaleInCancelled is used as part of the Event 'reason' when an offline
+	// TiKV store is brought up again since the replicas are increased.
+	ScaleInCancelled = "ScaleInCancelled"
+	// ScaleInRefused is used as part of the Event 'reason' when the TiKV
+	// replicas are fewer than the replicas of each region in PD. It is
+	// recorded once, the refusal is kept in the ScaleInRefused condition.
+	ScaleInRefused = "ScaleInRefused"
+
+	// ScalingInPD is used as part of the Event 'reason' when a PD member is
+	// deleted to be removed.
+	ScalingInPD = "ScalingInPD"
+	// ScaledInPD is used as part of the Event 'reason' when the pod of a
+	// deleted PD member is gone and its claim is deleted.
+	ScaledInPD = "ScaledInPD"
+
+	// scaleInRetryInterval is the interval the cluster is synced again while
+	// waiting for the regions to be migrated off the offline store.
+	scaleInRetryInterval = 30 * time.Second
+)
+
+// TiKV is not scaled in by the statefulset directly, the data would be lost
+// with the pods. The store of the pod with the greatest ordinal is deleted in
+// PD first, PD marks it offline and migrates its regions to the other stores.
+// The statefulset is scaled in by one once the store is tombstone, and the
+// claims of the pod are deleted after the pod is gone. The removal is
+// recorded in the status with the regions remaining.
+
+// getTiKVReplicas returns the replicas of the TiKV statefulset. It is the
+// desired replicas and the replacements of the failure stores, unless the
+// stores are being removed, which is done one at a time.
+func (c *Controller) getTiKVReplicas(tidb *api.TiDB, statefulSet *apps.StatefulSet) (int, error) {
+	replacements, err := c.syncTiKVFailover(tidb)
+	if err != nil {
+		return 0, err
+	}
+	desired := getReplicas(tidb.Spec.TiKVSpec.Replicas) + replacements
+	current := getReplicas(statefulSet.Spec.Replicas)
+	if record := tidb.Status.TiKV.ScalingIn; record != nil && record.State == pdapi.StoreStateTombstone &&
+		record.PodName == genPodName(tidb, componentTiKV, current-1) {
+		// The store is tombstone but the statefulset has not been scaled in.
+		return current - 1, nil
+	}
+	if tidb.Status.TiKV.ScalingIn != nil {
+		done, err := c.syncScalingIn(tidb, desired, current)
+		if err != nil || !done {
+			return current, err
+		}
+	}
+	if desired >= current {
+		if _, err := c.setScaleInRefused(tidb, false, ""); err != nil {
+			return current, err
+		}
+		return desired, nil
+	}
+	return c.scaleInTiKV(tidb, desired, current)
+}
+
+// setScaleInRefused sets the ScaleInRefused condition and writes the status
+// if the condition changed, so that the refusal is reported once. It returns
+// true if the scale-in is refused and it was not before.
+func (c *Controller) setScaleInRefused(tidb *api.TiDB, refused bool, message string) (bool, error) {
+	condition := getCondition(&tidb.Status, api.ClusterConditionScaleInRefused)
+	wasRefused := condition != nil && condition.Status == v1.ConditionTrue
+	if !refused {
+		if !wasRefused {
+			return false, nil
+		}
+		setCondition(&tidb.Status, api.ClusterConditionScaleInRefused, v1.ConditionFalse, "ScaleInAllowed", "")
+		return false, c.writeStatus(tidb)
+	}
+	if wasRefused && condition.Message == message {
+		return false, nil
+	}
+	setCondition(&tidb.Status, api.ClusterConditionScaleInRefused, v1.ConditionTrue, "TooFewStores", message)
+	return !wasRefused, c.writeStatus(tidb)
+}
+
+// syncScalingIn resumes the recorded removal of a store. It returns true if
+// there is no removal in progress anymore. The removal is cancelled if the
+// store is offline and the desired replicas include it again.
+func (c *Controller) syncScalingIn(tidb *api.TiDB, desired, current int) (bool, error) {
+	record := tidb.Status.TiKV.ScalingIn
+	if record.State != pdapi.StoreStateTombstone {
+		if desired < current {
+			// The progress is refreshed by scaleInTiKV.
+			return true, nil
+		}
+		if record.StoreID != 0 {
+			if err := c.getPDClient(tidb).SetStoreState(record.StoreID, pdapi.StoreStateUp); err != nil {
+				return false, fmt.Errorf("failed to bring up store %d: %v", record.StoreID, err)
+			}
+		}
+		c.recorder.Eventf(tidb, v1.EventTypeNormal, ScaleInCancelled,
+			"Cancelled removing store %d of pod %s", record.StoreID, record.PodName)
+		tidb.Status.TiKV.ScalingIn = nil
+		return true, nil
+	}
+
+	_, err := c.podLister.Pods(tidb.Namespace).Get(record.PodName)
+	if err == nil {
+		// The deletion of the pod syncs the cluster again.
+		glog.V(4).Infof("Waiting for pod %s/%s of the tombstone store to be deleted", tidb.Namespace, record.PodName)
+		return false, nil
+	}
+	if !errors.IsNotFound(err) {
+		return false, err
+	}
+	if !tidb.Spec.TiKVSpec.RetainPVCsOnScaleIn {
+		if err := c.deleteTiKVPodPVCs(tidb, record.PodName); err != nil {
+			return false, err
+		}
+	}
+	tidb.Status.TiKV.ScalingIn = nil
+	return true, c.writeStatus(tidb)
+}
+
+// scaleInTiKV removes the store of the pod with the greatest ordinal, it
+// returns the replicas of the statefulset. The statefulset is not scaled in
+// below the replicas of each region in PD.
+func (c *Controller) scaleInTiKV(tidb *api.TiDB, desired, current int) (int, error) {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return current, err
+	}
+	client := c.getPDClient(tidb)
+	config, err := client.GetReplicationConfig()
+	if err != nil {
+		return current, fmt.Errorf("failed to get the replication config of PD: %v", err)
+	}
+	if current <= config.MaxReplicas {
+		message := fmt.Sprintf("Refused to scale TiKV in to %d stores, PD keeps %d replicas of each region", desired, config.MaxReplicas)
+		refused, err := c.setScaleInRefused(tidb, true, message)
+		if err != nil {
+			return current, err
+		}
+		if refused {
+			c.recorder.Event(tidb, v1.EventTypeWarning, ScaleInRefused, message)
+		}
+		return current, nil
+	}
+	if _, err := c.setScaleInRefused(tidb, false, ""); err != nil {
+		return current, err
+	}
+
+	podName := genPodName(tidb, componentTiKV, current-1)
+	record := tidb.Status.TiKV.ScalingIn
+	if record == nil || record.PodName != podName {
+		record = &api.ScaleInStatus{PodName: podName, StartTime: metav1.Now()}
+		tidb.Status.TiKV.ScalingIn = record
+	}
+	var store *pdapi.StoreInfo
+	if record.StoreID != 0 {
+		// The tombstone stores are not listed.
+		if store, err = client.GetStore(record.StoreID); err != nil {
+			return current, fmt.Errorf("failed to get store %d from PD: %v", record.StoreID, err)
+		}
+	} else if store, err = getTiKVStore(client, genTiKVAddr(tidb, podName)); err != nil {
+		return current, err
+	}
+	if store == nil {
+		// The pod never registered a store, there is no data to migrate.
+		record.State = pdapi.StoreStateTombstone
+		return current - 1, c.writeStatus(tidb)
+	}
+
+	record.StoreID = store.Store.ID
+	record.State = store.Store.StateName
+	if store.Status != nil {
+		record.RegionCount = int32(store.Status.RegionCount)
+	}
+	switch store.Store.StateName {
+	case pdapi.StoreStateTombstone:
+		record.RegionCount = 0
+		if err := c.writeStatus(tidb); err != nil {
+			return current, err
+		}
+		c.recorder.Eventf(tidb, v1.EventTypeNormal, ScaledInTiKV,
+			"Store %d is tombstone, deleting pod %s", record.StoreID, podName)
+		return current - 1, nil
+	case pdapi.StoreStateOffline:
+	default:
+		c.recorder.Eventf(tidb, v1.EventTypeNormal, ScalingInTiKV,
+			"Removing store %d of pod %s", record.StoreID, podName)
+		if err := client.DeleteStore(record.StoreID); err != nil {
+			return current, fmt.Errorf("failed to delete store %d: %v", record.StoreID, err)
+		}
+		record.State = pdapi.StoreStateOffline
+	}
+	glog.V(4).Infof("Waiting for %d regions to be migrated off store %d of TiDB %s", record.RegionCount, record.StoreID, key)
+	c.workqueue.AddAfter(key, scaleInRetryInterval)
+	return current, nil
+}
+
+// deleteTiKVPodPVCs deletes the claims of the TiKV pod created from the claim
+// templates of the statefulset.
+func (c *Controller) deleteTiKVPodPVCs(tidb *api.TiDB, podName string) error {
+	claims := []string{tikvDataVolume}
+	for _, claim := range tidb.Spec.TiKVSpec.VolumeClaimTemplates {
+		claims = append(claims, claim.Name)
+	}
+	for _, claim := range claims {
+		name := fmt.Sprintf("%s-%s", claim, podName)
+		err := c.kubeclientset.CoreV1().PersistentVolumeClaims(tidb.Namespace).Delete(name, &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete persistent volume claim %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// getTiKVStore returns the store advertising the address, it is nil if there
+// is no such store.
+func getTiKVStore(client pdapi.Client, addr string) (*pdapi.StoreInfo, error) {
+	info, err := client.GetStores()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the stores from PD: %v", err)
+	}
+	for _, store := range info.Stores {
+		if store.Store != nil && store.Store.Address == addr {
+			return store, nil
+		}
+	}
+	return nil, nil
+}
+
+// PD is not scaled in by the statefulset directly either, the member of the
+// deleted pod would stay in PD and count against the quorum. The member of the
+// pod with the greatest ordinal is deleted in PD first, then the statefulset
+// is scaled in by one, and the claim of the pod is deleted after the pod is
+// gone. Only one member is removed at a time, and only if the healthy members
+// are still the majority without it. The removal is recorded in the status.
+
+// getPDReplicas returns the replicas of the PD statefulset. It is the desired
+// replicas, unless the members are being removed.
+func (c *Controller) getPDReplicas(tidb *api.TiDB, statefulSet *apps.StatefulSet) (int, error) {
+	desired := getReplicas(tidb.Spec.PDSpec.Replicas)
+	current := getReplicas(statefulSet.Spec.Replicas)
+	if record := tidb.Status.PD.ScalingIn; record != nil {
+		if record.PodName == genPodName(tidb, componentPD, current-1) {
+			// The statefulset has not been scaled in, the member may not
+			// have been deleted either.
+			if err := c.deletePDMember(tidb, record.PodName); err != nil {
+				return current, err
+			}
+			return current - 1, nil
+		}
+
+		_, err := c.podLister.Pods(tidb.Namespace).Get(record.PodName)
+		if err == nil {
+			// The deletion of the pod syncs the cluster again.
+			glog.V(4).Infof("Waiting for pod %s/%s of the deleted PD member to be deleted", tidb.Namespace, record.PodName)
+			return current, nil
+		}
+		if !errors.IsNotFound(err) {
+			return current, err
+		}
+		if err := c.deletePDPodPVC(tidb, record.PodName); err != nil {
+			return current, err
+		}
+		c.recorder.Eventf(tidb, v1.EventTypeNormal, ScaledInPD,
+			"Pod %s of the deleted PD member is gone", record.PodName)
+		tidb.Status.PD.ScalingIn = nil
+		if err := c.writeStatus(tidb); err != nil {
+			return current, err
+		}
+	}
+	if desired >= current {
+		return desired, nil
+	}
+	return c.scaleInPD(tidb, current)
+}
+
+// scaleInPD deletes the member of the pod with the greatest ordinal from PD,
+// it returns the replicas of the statefulset. The deletion is recorded before
+// the member is deleted, so that it is never left in the statefulset.
+func (c *Controller) scaleInPD(tidb *api.TiDB, current int) (int, error) {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return current, err
+	}
+	podName := genPodName(tidb, componentPD, current-1)
+	client := c.getPDClient(tidb)
+	members, err := client.GetHealth()
+	if err != nil {
+		return current, fmt.Errorf("failed to get the health of PD: %v", err)
+	}
+	if !hasPDQuorumWithout(members, podName) {
+		glog.Warningf("Waiting for the PD members of TiDB %s to be healthy to delete member %s", key, podName)
+		c.workqueue.AddAfter(key, scaleInRetryInterval)
+		return current, nil
+	}
+
+	tidb.Status.PD.ScalingIn = &api.PDScaleInStatus{PodName: podName, StartTime: metav1.Now()}
+	if err := c.writeStatus(tidb); err != nil {
+		return current, err
+	}
+	c.recorder.Eventf(tidb, v1.EventTypeNormal, ScalingInPD, "Deleting PD member %s", podName)
+	if err := c.deletePDMember(tidb, podName); err != nil {
+		return current, err
+	}
+	return current - 1, nil
+}
+
+// deletePDMember deletes the member from PD if it is still a member.
+func (c *Controller) deletePDMember(tidb *api.TiDB, name string) error {
+	client := c.getPDClient(tidb)
+	members, err := client.GetHealth()
+	if err != nil {
+		return fmt.Errorf("failed to get the health of PD: %v", err)
+	}
+	for _, member := range members {
+		if member.Name != name {
+			continue
+		}
+		if err := client.DeleteMember(name); err != nil {
+			return fmt.Errorf("failed to delete PD member %s: %v", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+	"github.com/gaocegege/kubetidb/pkg/pdapi"
+)
+
+func TestSyncScalesTiKVIn(t *testing.T) {
+	tidb := newTiDB("foo")
+	replicas := int32(4)
+	tidb.Spec.TiKVSpec.Replicas = &replicas
+	tidb, objects := newRunningCluster(t, tidb)
+	replicas = 3
+	tidb.Spec.TiKVSpec.Replicas = &replicas
+
+	// The store of the pod with the greatest ordinal is removed in PD, the
+	// statefulset keeps the pod until the store is tombstone.
+	f := newClusterFixture(t, tidb, objects)
+	f.sync(tidb)
+	if state := f.pd.Store(4).Store.StateName; state != pdapi.StoreStateOffline {
+		t.Errorf("Expected store 4 to be offline, got %s", state)
+	}
+	tidb = f.getTiDB(tidb)
+	record := tidb.Status.TiKV.ScalingIn
+	if record == nil || record.PodName != "foo-tikv-3" || record.StoreID != 4 || record.State != pdapi.StoreStateOffline {
+		t.Fatalf("Expected the removal of store 4 of foo-tikv-3 to be recorded, got %+v", record)
+	}
+	if got := countEvents(f, ScalingInTiKV); got != 1 {
+		t.Errorf("Expected the removal to be reported once, got %d", got)
+	}
+	if got := getReplicas(f.getStatefulSet("foo-tikv").Spec.Replicas); got != 4 {
+		t.Errorf("Expected the TiKV statefulset to keep 4 replicas, got %d", got)
+	}
+	f.close()
+
+	// The statefulset is scaled in once the regions are migrated.
+	f = newClusterFixture(t, tidb, objects)
+	defer f.close()
+	f.pd.Store(4).Store.StateName = pdapi.StoreStateTombstone
+	f.sync(tidb)
+	if got := getReplicas(f.getStatefulSet("foo-tikv").Spec.Replicas); got != 3 {
+		t.Errorf("Expected the TiKV statefulset to be scaled in to 3 replicas, got %d", got)
+	}
+	if record := f.getTiDB(tidb).Status.TiKV.ScalingIn; record == nil || record.State != pdapi.StoreStateTombstone {
+		t.Errorf("Expected the store to be recorded tombstone, got %+v", record)
+	}
+	if got := countEvents(f, ScaledInTiKV); got != 1 {
+		t.Errorf("Expected the scale-in to be reported once, got %d", got)
+	}
+}
+
+func TestSyncRefusesTiKVScaleInBelowMaxReplicas(t *testing.T) {
+	tidb, objects := newRunningCluster(t, newTiDB("foo"))
+	replicas := int32(2)
+	tidb.Spec.TiKVSpec.Replicas = &replicas
+
+	for i := 0; i < 2; i++ {
+		f := newClusterFixture(t, tidb, objects)
+		f.sync(tidb)
+		tidb = f.getTiDB(tidb)
+		condition := getCondition(&tidb.Status, api.ClusterConditionScaleInRefused)
+		if condition == nil || condition.Status != v1.ConditionTrue {
+			t.Errorf("Expected the scale-in to be refused, got %+v", condition)
+		}
+		// The refusal is reported once, it is kept in the condition.
+		expected := 0
+		if i == 0 {
+			expected = 1
+		}
+		if got := countEvents(f, ScaleInRefused); got != expected {
+			t.Errorf("Expected the refusal to be reported %d times in sync %d, got %d", expected, i, got)
+		}
+		if state := f.pd.Store(3).Store.StateName; state != pdapi.StoreStateUp {
+			t.Errorf("Expected store 3 to stay up, got %s", state)
+		}
+		if got := getReplicas(f.getStatefulSet("foo-tikv").Spec.Replicas); got != api.DefaultTiKVReplicas {
+			t.Errorf("Expected the TiKV statefulset to keep %d replicas, got %d", api.DefaultTiKVReplicas, got)
+		}
+		f.close()
+	}
+}
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/cache"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+// syncServices creates the services which do not exist yet, and updates the
+// existing ones if the rendered spec changed. The annotations removed from
+// the rendered services are removed from the existing ones.
+func (c *Controller) syncServices(tidb *api.TiDB, services []*v1.Service) error {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return err
+	}
+
+	for _, desired := range services {
+		setManagedAnnotations(&desired.ObjectMeta)
+		setSpecHash(&desired.ObjectMeta, struct {
+			Annotations map[string]string
+			Spec        v1.ServiceSpec
+		}{desired.Annotations, desired.Spec})
+
+		existing, err := c.serviceLister.Services(tidb.Namespace).Get(desired.Name)
+		if errors.IsNotFound(err) {
+			c.raiseExpectations(key, 1, 0)
+			if _, err := c.kubeclientset.CoreV1().Services(tidb.Namespace).Create(desired); err != nil {
+				// The service informer won't observe the creation, so
+				// decrement the expected number of creates.
+				c.expectations.CreationObserved(key)
+				if !errors.IsAlreadyExists(err) {
+					return fmt.Errorf("failed to create service %s: %v", desired.Name, err)
+				}
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !metav1.IsControlledBy(existing, tidb) {
+			return fmt.Errorf("service %s already exists and is not owned by %s", existing.Name, key)
+		}
+
+		if existing.Annotations[annotationSpecHash] == desired.Annotations[annotationSpecHash] {
+			continue
+		}
+		glog.V(4).Infof("Update service %s/%s", existing.Namespace, existing.Name)
+		service := existing.DeepCopy()
+		service.Annotations = mergeAnnotations(service.Annotations, desired.Annotations)
+		updateServiceSpec(&service.Spec, &desired.Spec)
+		if _, err := c.kubeclientset.CoreV1().Services(tidb.Namespace).Update(service); err != nil {
+			return fmt.Errorf("failed to update service %s: %v", service.Name, err)
+		}
+	}
+	return nil
+}
+
+// updateServiceSpec updates the existing spec to the desired one. The
+// cluster IP and the allocated node ports are kept if they are still valid.
+func updateServiceSpec(existing, desired *v1.ServiceSpec) {
+	exposed := desired.Type == v1.ServiceTypeNodePort || desired.Type == v1.ServiceTypeLoadBalancer
+
+	nodePorts := make(map[string]int32)
+	for _, port := range existing.Ports {
+		nodePorts[port.Name] = port.NodePort
+	}
+	ports := make([]v1.ServicePort, 0, len(desired.Ports))
+	for _, port := range desired.Ports {
+		if exposed {
+			port.NodePort = nodePorts[port.Name]
+		}
+		ports = append(ports, port)
+	}
+
+	if desired.Type != v1.ServiceTypeLoadBalancer || desired.ExternalTrafficPolicy != v1.ServiceExternalTrafficPolicyTypeLocal {
+		existing.HealthCheckNodePort = 0
+	}
+	existing.Type = desired.Type
+	existing.Ports = ports
+	existing.Selector = desired.Selector
+	existing.ExternalTrafficPolicy = desired.ExternalTrafficPolicy
+	existing.PublishNotReadyAddresses = desired.PublishNotReadyAddresses
+}
+
+// newService returns the service of the component which is owned by the
+// TiDB cluster.
+func newService(tidb *api.TiDB, component componentType, name string, ports []v1.ServicePort) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       tidb.Namespace,
+			Labels:          genLabels(tidb, component),
+			OwnerReferences: []metav1.OwnerReference{*genOwnerReference(tidb)},
+		},
+		Spec: v1.ServiceSpec{
+			Selector: genLabels(tidb, component),
+			Ports:    ports,
+		},
+	}
+}
+
+// newPeerService returns the headless service which governs the DNS names of
+// the pods of the component.
+func newPeerService(tidb *api.TiDB, component componentType, ports []v1.ServicePort) *v1.Service {
+	service := newService(tidb, component, genPeerServiceName(tidb, component), ports)
+	service.Annotations = map[string]string{annotationTolerateUnready: "true"}
+	service.Spec.ClusterIP = v1.ClusterIPNone
+	service.Spec.PublishNotReadyAddresses = true
+	return service
+}
+
+// genServicePort returns the service port which targets the same port of the
+// pods.
+func genServicePort(name string, port int32) v1.ServicePort {
+	return v1.ServicePort{
+		Name:       name,
+		Port:       port,
+		TargetPort: intstr.FromInt(int(port)),
+		Protocol:   v1.ProtocolTCP,
+	}
+}
+
+// deleteService deletes the service owned by the TiDB.
+func (c *Controller) deleteService(tidb *api.TiDB, service *v1.Service) error {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return err
+	}
+
+	c.raiseExpectations(key, 0, 1)
+	if err := c.kubeclientset.CoreV1().Services(tidb.Namespace).Delete(service.Name, &metav1.DeleteOptions{}); err != nil {
+		// The service informer won't observe the deletion, so decrement
+		// the expected number of deletes.
+		c.expectations.DeletionObserved(key)
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete service %s: %v", service.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	apps "k8s.io/api/apps/v1beta2"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	hashutil "k8s.io/kubernetes/pkg/util/hash"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+const (
+	// annotationSpecHash is the annotation key of the hash of the spec
+	// rendered by the controller, the object is updated only if the hash
+	// changes since the API server fills in the defaults.
+	annotationSpecHash = "kubetidb.gaocegege.com/spec-hash"
+	// annotationManagedAnnotations is the annotation key of the keys of the
+	// annotations set by the controller, they are removed from the object
+	// once they are not desired anymore. The annotations added by others
+	// are kept.
+	annotationManagedAnnotations = "kubetidb.gaocegege.com/managed-annotations"
+)
+
+// syncStatefulSet creates the statefulset if it does not exist, or updates
+// it if the rendered spec changed. The statefulset is returned, it is nil if
+// it is being created.
+func (c *Controller) syncStatefulSet(tidb *api.TiDB, desired *apps.StatefulSet) (*apps.StatefulSet, error) {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return nil, err
+	}
+	setSpecHash(&desired.ObjectMeta, desired.Spec)
+
+	existing, err := c.statefulSetLister.StatefulSets(tidb.Namespace).Get(desired.Name)
+	if errors.IsNotFound(err) {
+		c.raiseExpectations(key, 1, 0)
+		if _, err := c.kubeclientset.AppsV1beta2().StatefulSets(tidb.Namespace).Create(desired); err != nil {
+			// The statefulset informer won't observe the creation, so
+			// decrement the expected number of creates.
+			c.expectations.CreationObserved(key)
+			if !errors.IsAlreadyExists(err) {
+				return nil, fmt.Errorf("failed to create statefulset %s: %v", desired.Name, err)
+			}
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !metav1.IsControlledBy(existing, tidb) {
+		return nil, fmt.Errorf("statefulset %s already exists and is not owned by %s", existing.Name, key)
+	}
+
+	if existing.Annotations[annotationSpecHash] == desired.Annotations[annotationSpecHash] {
+		return existing, nil
+	}
+	glog.V(4).Infof("Update statefulset %s/%s", existing.Namespace, existing.Name)
+	statefulSet := existing.DeepCopy()
+	statefulSet.Annotations = mergeAnnotations(statefulSet.Annotations, desired.Annotations)
+	statefulSet.Spec.Replicas = desired.Spec.Replicas
+	statefulSet.Spec.Template = desired.Spec.Template
+	statefulSet.Spec.UpdateStrategy = desired.Spec.UpdateStrategy
+	return c.kubeclientset.AppsV1beta2().StatefulSets(tidb.Namespace).Update(statefulSet)
+}
+
+// deleteStatefulSet deletes the statefulset owned by the TiDB, the pods are
+// deleted by the garbage collector.
+func (c *Controller) deleteStatefulSet(tidb *api.TiDB, statefulSet *apps.StatefulSet) error {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return err
+	}
+
+	propagation := metav1.DeletePropagationBackground
+	c.raiseExpectations(key, 0, 1)
+	err = c.kubeclientset.AppsV1beta2().StatefulSets(tidb.Namespace).Delete(statefulSet.Name, &metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if err != nil {
+		// The statefulset informer won't observe the deletion, so
+		// decrement the expected number of deletes.
+		c.expectations.DeletionObserved(key)
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete statefulset %s: %v", statefulSet.Name, err)
+		}
+	}
+	return nil
+}
+
+// newStatefulSet returns the statefulset of the component which is owned by
+// the TiDB cluster. The pods are governed by the headless peer service, and
+// they are upgraded by the controller one at a time.
+func newStatefulSet(tidb *api.TiDB, component componentType, replicas int, template *v1.PodTemplateSpec) *apps.StatefulSet {
+	replicasInt32 := int32(replicas)
+	return &apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            genName(tidb, component),
+			Namespace:       tidb.Namespace,
+			Labels:          genLabels(tidb, component),
+			OwnerReferences: []metav1.OwnerReference{*genOwnerReference(tidb)},
+		},
+		Spec: apps.StatefulSetSpec{
+			Replicas:    &replicasInt32,
+			Selector:    &metav1.LabelSelector{MatchLabels: genLabels(tidb, component)},
+			ServiceName: genPeerServiceName(tidb, component),
+			Template:    *template,
+			// All the members are started at the same time, they could not
+			// be ready one by one before the cluster is bootstrapped.
+			PodManagementPolicy: apps.ParallelPodManagement,
+			UpdateStrategy: apps.StatefulSetUpdateStrategy{
+				Type: apps.OnDeleteStatefulSetStrategyType,
+			},
+		},
+	}
+}
+
+// setSpecHash sets the hash of the spec in the annotations.
+func setSpecHash(meta *metav1.ObjectMeta, spec interface{}) {
+	hasher := fnv.New32a()
+	hashutil.DeepHashObject(hasher, spec)
+	if meta.Annotations == nil {
+		meta.Annotations = make(map[string]string)
+	}
+	meta.Annotations[annotationSpecHash] = fmt.Sprintf("%x", hasher.Sum32())
+}
+
+// setManagedAnnotations records the keys of the annotations in the
+// annotations, it is called before the spec hash is set.
+func setManagedAnnotations(meta *metav1.ObjectMeta) {
+	keys := make([]string, 0, len(meta.Annotations))
+	for k := range meta.Annotations {
+		if k != annotationManagedAnnotations {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if meta.Annotations == nil {
+		meta.Annotations = make(map[string]string)
+	}
+	meta.Annotations[annotationManagedAnnotations] = strings.Join(keys, ",")
+}
+
+// mergeAnnotations returns the existing annotations overridden by the desired
+// ones. The managed annotations recorded in the existing ones are removed if
+// they are not desired anymore.
+func mergeAnnotations(existing, desired map[string]string) map[string]string {
+	annotations := make(map[string]string)
+	for k, v := range existing {
+		annotations[k] = v
+	}
+	if managed := existing[annotationManagedAnnotations]; managed != "" {
+		for _, k := range strings.Split(managed, ",") {
+			if _, ok := desired[k]; !ok {
+				delete(annotations, k)
+			}
+		}
+	}
+	for k, v := range desired {
+		annotations[k] = v
+	}
+	return annotations
+}
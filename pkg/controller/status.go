@@ -0,0 +1,272 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/golang/glog"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+// updateStatus computes the status of the TiDB from the owned pods, and
+// writes it through the status subresource if it changed. An Event is
+// recorded for every transition of the phase.
+func (c *Controller) updateStatus(tidb *api.TiDB) error {
+	status, err := c.computeStatus(tidb)
+	if err != nil {
+		return err
+	}
+	// The status of the TiDB is modified during the sync, e.g. the failure
+	// stores, it is compared with the one in the cache which is persisted.
+	observed := &tidb.Status
+	if cached, err := c.tidbLister.TiDBs(tidb.Namespace).Get(tidb.Name); err == nil {
+		observed = &cached.Status
+	}
+	if equality.Semantic.DeepEqual(*status, *observed) {
+		return nil
+	}
+
+	tidbCopy := tidb.DeepCopy()
+	tidbCopy.Status = *status
+	glog.V(4).Infof("Update status of TiDB %s/%s: %s", tidb.Namespace, tidb.Name, status.Phase)
+	if _, err := c.tidbClientset.KubetidbV1beta1().TiDBs(tidb.Namespace).UpdateStatus(tidbCopy); err != nil {
+		return err
+	}
+
+	if status.Phase != observed.Phase {
+		c.recorder.Eventf(tidb, phaseEventType(status.Phase), string(status.Phase),
+			"Cluster phase changed from %q to %q", observed.Phase, status.Phase)
+	}
+	return nil
+}
+
+// writeStatus writes the status of the TiDB right away and replaces the TiDB
+// with the updated one. It records the progress which must survive a failed
+// sync, e.g. the eviction of the region leaders of a TiKV store.
+func (c *Controller) writeStatus(tidb *api.TiDB) error {
+	updated, err := c.tidbClientset.KubetidbV1beta1().TiDBs(tidb.Namespace).UpdateStatus(tidb)
+	if err != nil {
+		return err
+	}
+	// The defaults are not persisted without the mutating admission webhook.
+	api.SetObjectDefaults_TiDB(updated)
+	*tidb = *updated
+	return nil
+}
+
+// computeStatus returns the status derived from the owned pods of all the
+// components.
+func (c *Controller) computeStatus(tidb *api.TiDB) (*api.ClusterStatus, error) {
+	status := tidb.Status.DeepCopy()
+	if status.StartTime == nil {
+		now := metav1.Now()
+		status.StartTime = &now
+	}
+	status.Instances = nil
+
+	observation := &clusterObservation{deleting: tidb.DeletionTimestamp != nil}
+	var failedPods []string
+	// upgrading is the first component not upgraded yet, the components are
+	// upgraded one by one.
+	var upgrading componentType
+	var upgradeMessage string
+	for _, component := range []componentType{componentPD, componentTiKV, componentTiDB} {
+		replicas := getComponentReplicas(tidb, component)
+		observation.desired += replicas
+
+		pods, err := c.podLister.Pods(tidb.Namespace).List(genSelector(tidb, component))
+		if err != nil {
+			return nil, err
+		}
+		upgraded, message, err := c.getComponentUpgrade(tidb, component)
+		if err != nil {
+			return nil, err
+		}
+		if !upgraded {
+			observation.upgrading = true
+			if upgrading == "" {
+				upgrading, upgradeMessage = component, message
+			}
+		}
+		existing := 0
+		for _, pod := range pods {
+			status.Instances = append(status.Instances, api.InstanceStatus{
+				Name:  pod.Name,
+				State: getInstanceState(pod),
+			})
+			if pod.DeletionTimestamp != nil {
+				// The pod is being removed by scaling in.
+				observation.scaling = true
+				continue
+			}
+			existing++
+			switch {
+			case pod.Status.Phase == v1.PodFailed:
+				observation.failed++
+				failedPods = append(failedPods, pod.Name)
+			case isPodReady(pod):
+				observation.ready++
+			}
+		}
+		if existing != replicas {
+			observation.scaling = true
+		}
+		if component == componentTiDB {
+			// The status of the TiDB servers backs the scale subresource.
+			status.TiDB.Replicas = int32(existing)
+			status.TiDB.Selector = genSelector(tidb, componentTiDB).String()
+		}
+		observation.existing += existing
+	}
+
+	sort.Slice(status.Instances, func(i, j int) bool {
+		return status.Instances[i].Name < status.Instances[j].Name
+	})
+	status.Phase = nextPhase(status.Phase, observation)
+
+	if isAvailablePhase(status.Phase) {
+		setCondition(status, api.ClusterConditionAvailable, v1.ConditionTrue, "ClusterAvailable",
+			fmt.Sprintf("%d of %d instances are ready", observation.ready, observation.desired))
+	} else {
+		setCondition(status, api.ClusterConditionAvailable, v1.ConditionFalse, "ClusterUnavailable",
+			fmt.Sprintf("%d of %d instances are ready", observation.ready, observation.desired))
+	}
+	setUpgradingCondition(tidb, status, upgrading, upgradeMessage)
+	if observation.failed > 0 {
+		setCondition(status, api.ClusterConditionFailed, v1.ConditionTrue, "InstancesFailed",
+			fmt.Sprintf("Failed instances: %v", failedPods))
+	} else if getCondition(status, api.ClusterConditionFailed) != nil {
+		setCondition(status, api.ClusterConditionFailed, v1.ConditionFalse, "NoInstanceFailed", "")
+	}
+	return status, nil
+}
+
+// getComponentReplicas returns the desired replicas of the component, the
+// replacements of the failure stores and servers are desired as well.
+func getComponentReplicas(tidb *api.TiDB, component componentType) int {
+	switch component {
+	case componentPD:
+		return getReplicas(tidb.Spec.PDSpec.Replicas)
+	case componentTiKV:
+		replicas := getReplicas(tidb.Spec.TiKVSpec.Replicas)
+		for _, failure := range tidb.Status.TiKV.FailureStores {
+			if failure.Replaced {
+				replicas++
+			}
+		}
+		return replicas
+	case componentTiDB:
+		return getReplicas(tidb.Spec.TiDBSpec.Replicas) + getTiDBReplacements(tidb)
+	}
+	return 0
+}
+
+// getComponentTemplate returns the pod template of the component.
+func getComponentTemplate(tidb *api.TiDB, component componentType) *v1.PodTemplateSpec {
+	switch component {
+	case componentPD:
+		return tidb.Spec.PDSpec.Template
+	case componentTiKV:
+		return tidb.Spec.TiKVSpec.Template
+	case componentTiDB:
+		return tidb.Spec.TiDBSpec.Template
+	}
+	return nil
+}
+
+// getComponentUpgrade returns true if all the pods of the component run the
+// latest template, or a message of the progress otherwise.
+func (c *Controller) getComponentUpgrade(tidb *api.TiDB, component componentType) (bool, string, error) {
+	if component == componentTiDB {
+		deployment, err := c.deploymentLister.Deployments(tidb.Namespace).Get(genName(tidb, component))
+		if errors.IsNotFound(err) {
+			return true, "", nil
+		}
+		if err != nil {
+			return false, "", err
+		}
+		if isDeploymentUpgraded(deployment) {
+			return true, "", nil
+		}
+		return false, fmt.Sprintf("%d of %d pods of %s are upgraded",
+			deployment.Status.UpdatedReplicas, deployment.Status.Replicas, component), nil
+	}
+
+	progress, err := c.getStatefulSetProgress(tidb, component)
+	if err != nil {
+		return false, "", err
+	}
+	if progress.done() {
+		return true, "", nil
+	}
+	return false, fmt.Sprintf("%d of %d pods of %s are upgraded",
+		len(progress.pods)-len(progress.outdated), len(progress.pods), component), nil
+}
+
+// getInstanceState returns the phase of the pod, or NotReady if it is
+// running but not ready.
+func getInstanceState(pod *v1.Pod) api.InstanceState {
+	if pod.Status.Phase == v1.PodRunning && !isPodReady(pod) {
+		return api.InstanceStateNotReady
+	}
+	return api.InstanceState(pod.Status.Phase)
+}
+
+// isPodReady returns true if the pod is running and ready.
+func isPodReady(pod *v1.Pod) bool {
+	if pod.Status.Phase != v1.PodRunning || pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// getCondition returns the condition with the given type, or nil if there
+// is no such condition.
+func getCondition(status *api.ClusterStatus, conditionType api.ClusterConditionType) *api.ClusterCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == conditionType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// setCondition updates the condition with the given type. LastUpdateTime is
+// only bumped when the condition changes, and LastTransitionTime is only
+// bumped when the status of the condition changes.
+func setCondition(status *api.ClusterStatus, conditionType api.ClusterConditionType, conditionStatus v1.ConditionStatus, reason, message string) {
+	now := metav1.Now()
+
+	condition := getCondition(status, conditionType)
+	if condition == nil {
+		status.Conditions = append(status.Conditions, api.ClusterCondition{
+			Type:               conditionType,
+			Status:             conditionStatus,
+			LastUpdateTime:     now,
+			LastTransitionTime: now,
+			Reason:             reason,
+			Message:            message,
+		})
+		return
+	}
+
+	if condition.Status != conditionStatus {
+		condition.LastTransitionTime = now
+	}
+	if condition.Status != conditionStatus || condition.Reason != reason || condition.Message != message {
+		condition.LastUpdateTime = now
+	}
+	condition.Status = conditionStatus
+	condition.Reason = reason
+	condition.Message = message
+}
@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"fmt"
+
+	apps "k8s.io/api/apps/v1beta2"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+// syncTiDB reconciles the stateless TiDB servers of the cluster into a
+// deployment and the service exposing them. The rollout of the deployment is
+// paused while PD and TiKV are being upgraded, and extra servers are added
+// for the unhealthy ones.
+func (c *Controller) syncTiDB(tidb *api.TiDB, paused bool) error {
+	if err := c.syncServices(tidb, []*v1.Service{newTiDBService(tidb)}); err != nil {
+		return err
+	}
+	replacements, err := c.syncTiDBFailover(tidb)
+	if err != nil {
+		return err
+	}
+	deployment := newTiDBDeployment(tidb, getReplicas(tidb.Spec.TiDBSpec.Replicas)+replacements, paused)
+	if err := c.syncConfig(tidb, componentTiDB, &deployment.Spec.Template); err != nil {
+		return err
+	}
+	return c.syncDeployment(tidb, deployment)
+}
+
+// newTiDBDeployment returns the deployment of the TiDB servers. A new server
+// is started and ready before an old one is stopped, one at a time.
+func newTiDBDeployment(tidb *api.TiDB, replicas int, paused bool) *apps.Deployment {
+	template := newPodTemplate(tidb, componentTiDB)
+
+	container := getContainer(&template.Spec, componentTiDB)
+	container.Args = mergeArgs(genTiDBArgs(tidb), container.Args)
+	if container.ReadinessProbe == nil {
+		container.ReadinessProbe = &v1.Probe{
+			Handler: v1.Handler{
+				HTTPGet: &v1.HTTPGetAction{
+					Path: "/status",
+					Port: intstr.FromInt(tidbStatusPort),
+				},
+			},
+		}
+	}
+
+	deployment := newDeployment(tidb, componentTiDB, replicas, template)
+	maxSurge := intstr.FromInt(1)
+	maxUnavailable := intstr.FromInt(0)
+	deployment.Spec.Strategy = apps.DeploymentStrategy{
+		Type: apps.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &apps.RollingUpdateDeployment{
+			MaxSurge:       &maxSurge,
+			MaxUnavailable: &maxUnavailable,
+		},
+	}
+	deployment.Spec.Paused = paused
+	return deployment
+}
+
+// newTiDBService returns the service exposing the MySQL port and the status
+// port of the TiDB servers, it is configured by the service spec.
+func newTiDBService(tidb *api.TiDB) *v1.Service {
+	spec := tidb.Spec.TiDBSpec.Service
+
+	service := newService(tidb, componentTiDB, genName(tidb, componentTiDB), []v1.ServicePort{
+		genServicePort("mysql", tidbPort),
+		genServicePort("status", tidbStatusPort),
+	})
+	if len(spec.Annotations) != 0 {
+		service.Annotations = make(map[string]string)
+		for k, v := range spec.Annotations {
+			service.Annotations[k] = v
+		}
+	}
+	service.Spec.Type = spec.Type
+	if service.Spec.Type == "" {
+		service.Spec.Type = v1.ServiceTypeClusterIP
+	}
+	if service.Spec.Type == v1.ServiceTypeNodePort || service.Spec.Type == v1.ServiceTypeLoadBalancer {
+		service.Spec.ExternalTrafficPolicy = spec.ExternalTrafficPolicy
+	}
+	return service
+}
+
+// genTiDBArgs returns the arguments of the TiDB servers.
+func genTiDBArgs(tidb *api.TiDB) []string {
+	return []string{
+		"--store=tikv",
+		fmt.Sprintf("--path=%s", genPDAddr(tidb)),
+		fmt.Sprintf("-P=%d", tidbPort),
+		fmt.Sprintf("--status=%d", tidbStatusPort),
+	}
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"fmt"
+
+	apps "k8s.io/api/apps/v1beta2"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+const (
+	tikvDataVolume = "tikv-data"
+
+	// envPodName is the environment variable of the pod name, it is
+	// expanded in the arguments by the kubelet.
+	envPodName = "POD_NAME"
+)
+
+// defaultTiKVStorageSize is the size of the persistent volume of each store
+// if it is not given.
+var defaultTiKVStorageSize = resource.MustParse(api.DefaultTiKVStorageSize)
+
+// syncTiKV reconciles the TiKV stores of the cluster into a statefulset and
+// the headless peer service. The statefulset is scaled in one store at a
+// time, after the store is removed from PD.
+func (c *Controller) syncTiKV(tidb *api.TiDB) (*apps.StatefulSet, error) {
+	services := []*v1.Service{
+		newPeerService(tidb, componentTiKV, []v1.ServicePort{
+			genServicePort("server", tikvPort),
+		}),
+	}
+	if err := c.syncServices(tidb, services); err != nil {
+		return nil, err
+	}
+
+	statefulSet := newTiKVStatefulSet(tidb)
+	if err := c.syncConfig(tidb, componentTiKV, &statefulSet.Spec.Template); err != nil {
+		return nil, err
+	}
+	existing, err := c.statefulSetLister.StatefulSets(tidb.Namespace).Get(statefulSet.Name)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		// The stores are removed through PD before the pods are deleted.
+		replicas, err := c.getTiKVReplicas(tidb, existing)
+		if err != nil {
+			return nil, err
+		}
+		replicasInt32 := int32(replicas)
+		statefulSet.Spec.Replicas = &replicasInt32
+	}
+	return c.syncStatefulSet(tidb, statefulSet)
+}
+
+// newTiKVStatefulSet returns the statefulset of TiKV, every store has its
+// own persistent volume.
+func newTiKVStatefulSet(tidb *api.TiDB) *apps.StatefulSet {
+	spec := tidb.Spec.TiKVSpec
+	template := newPodTemplate(tidb, componentTiKV)
+
+	container := getContainer(&template.Spec, componentTiKV)
+	container.Args = mergeArgs(genTiKVArgs(tidb), container.Args)
+	container.Env = append(container.Env, v1.EnvVar{
+		Name: envPodName,
+		ValueFrom: &v1.EnvVarSource{
+			FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.name"},
+		},
+	})
+	container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+		Name:      tikvDataVolume,
+		MountPath: tikvDataDir,
+	})
+
+	statefulSet := newStatefulSet(tidb, componentTiKV, getReplicas(spec.Replicas), template)
+	statefulSet.Spec.VolumeClaimTemplates = append([]v1.PersistentVolumeClaim{newTiKVDataClaim(tidb)}, spec.VolumeClaimTemplates...)
+	return statefulSet
+}
+
+// newTiKVDataClaim returns the claim template of the data volume of the
+// stores. The claims are labelled with the cluster so they could be found
+// when the cluster is torn down.
+func newTiKVDataClaim(tidb *api.TiDB) v1.PersistentVolumeClaim {
+	spec := tidb.Spec.TiKVSpec
+	size := defaultTiKVStorageSize
+	if spec.StorageSize != nil {
+		size = *spec.StorageSize
+	}
+
+	return v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   tikvDataVolume,
+			Labels: genLabels(tidb, componentTiKV),
+		},
+		Spec: v1.PersistentVolumeClaimSpec{
+			AccessModes:      []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+			StorageClassName: spec.StorageClassName,
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceStorage: size},
+			},
+		},
+	}
+}
+
+// genTiKVArgs returns the arguments of the TiKV stores. The stores are
+// pointed to the PD client service, and advertise the stable DNS names of
+// the pods.
+func genTiKVArgs(tidb *api.TiDB) []string {
+	return []string{
+		fmt.Sprintf("--pd=%s", genPDAddr(tidb)),
+		fmt.Sprintf("--addr=0.0.0.0:%d", tikvPort),
+		fmt.Sprintf("--advertise-addr=$(%s).%s.%s.svc:%d",
+			envPodName, genPeerServiceName(tidb, componentTiKV), tidb.Namespace, tikvPort),
+		fmt.Sprintf("--data-dir=%s", tikvDataDir),
+	}
+}
@@ -0,0 +1,279 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	apps "k8s.io/api/apps/v1beta2"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/controller"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+const (
+	// UpgradingPod is used as part of the Event 'reason' when a pod is
+	// deleted to be recreated with the new template.
+	UpgradingPod = "UpgradingPod"
+	// TransferringPDLeader is used as part of the Event 'reason' when the
+	// PD leadership is moved off a pod before it is upgraded.
+	TransferringPDLeader = "TransferringPDLeader"
+
+	// upgradeRetryInterval is the interval the cluster is synced again while
+	// waiting for the upgraded members to be healthy in PD.
+	upgradeRetryInterval = 10 * time.Second
+)
+
+// The PD members and TiKV stores are upgraded by the controller one pod at a
+// time, their statefulsets use the OnDelete update strategy. A pod is
+// deleted only if all the pods of the component are ready and healthy in
+// PD, the statefulset then recreates it with the new template. The TiDB
+// servers are rolled by their deployment once PD and TiKV are upgraded.
+
+// upgradeProgress is the progress of the rolling upgrade of a component
+// managed by a statefulset.
+type upgradeProgress struct {
+	component   componentType
+	statefulSet *apps.StatefulSet
+	// observed is false if the statefulset controller has not observed the
+	// latest template, the pods could not be compared with it yet.
+	observed bool
+	// pods is all the pods of the statefulset.
+	pods []*v1.Pod
+	// outdated is the pods not running the latest template, the ones with
+	// greater ordinals come first.
+	outdated []*v1.Pod
+}
+
+// done returns true if all the pods run the latest template.
+func (p *upgradeProgress) done() bool {
+	return p.observed && len(p.outdated) == 0
+}
+
+// getUpgradeProgress returns the upgrade progress of the statefulset of the
+// component. A statefulset which does not exist yet has nothing to upgrade.
+func (c *Controller) getUpgradeProgress(tidb *api.TiDB, component componentType, statefulSet *apps.StatefulSet) (*upgradeProgress, error) {
+	progress := &upgradeProgress{component: component, statefulSet: statefulSet, observed: true}
+	if statefulSet == nil {
+		return progress, nil
+	}
+	pods, err := c.podLister.Pods(tidb.Namespace).List(genSelector(tidb, component))
+	if err != nil {
+		return nil, err
+	}
+	progress.pods = pods
+	progress.observed = statefulSet.Status.ObservedGeneration >= statefulSet.Generation &&
+		statefulSet.Status.UpdateRevision != ""
+	for _, pod := range pods {
+		if !progress.observed || pod.Labels[apps.StatefulSetRevisionLabel] != statefulSet.Status.UpdateRevision {
+			progress.outdated = append(progress.outdated, pod)
+		}
+	}
+	sort.Slice(progress.outdated, func(i, j int) bool {
+		return getPodOrdinal(progress.outdated[i]) > getPodOrdinal(progress.outdated[j])
+	})
+	return progress, nil
+}
+
+// getStatefulSetProgress returns the upgrade progress of the component from
+// the statefulset in the lister.
+func (c *Controller) getStatefulSetProgress(tidb *api.TiDB, component componentType) (*upgradeProgress, error) {
+	statefulSet, err := c.statefulSetLister.StatefulSets(tidb.Namespace).Get(genName(tidb, component))
+	if errors.IsNotFound(err) {
+		statefulSet, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.getUpgradeProgress(tidb, component, statefulSet)
+}
+
+// upgradeCluster upgrades the first component not upgraded yet in the order
+// of PD and TiKV, nothing is done if the cluster is paused. The eviction of
+// the region leaders of a TiKV store is finished first, even if the upgrade
+// has been paused or completed since it started.
+func (c *Controller) upgradeCluster(tidb *api.TiDB, pdProgress, tikvProgress *upgradeProgress) error {
+	if tidb.Status.TiKV.EvictingLeader != nil {
+		return c.syncEvictingLeader(tidb, tikvProgress)
+	}
+	for _, progress := range []*upgradeProgress{pdProgress, tikvProgress} {
+		if progress.done() {
+			continue
+		}
+		if tidb.Spec.Paused {
+			glog.V(4).Infof("Upgrade of TiDB %s/%s is paused", tidb.Namespace, tidb.Name)
+			return nil
+		}
+		return c.upgradeStatefulSet(tidb, progress)
+	}
+	return nil
+}
+
+// upgradeStatefulSet deletes the next outdated pod of the component if all
+// the pods are ready and healthy.
+func (c *Controller) upgradeStatefulSet(tidb *api.TiDB, progress *upgradeProgress) error {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return err
+	}
+	if !progress.observed || !c.podExpectations.SatisfiedExpectations(key) {
+		// The events of the statefulset or the deleted pod sync the
+		// cluster again.
+		return nil
+	}
+	replicas := 1
+	if progress.statefulSet.Spec.Replicas != nil {
+		replicas = int(*progress.statefulSet.Spec.Replicas)
+	}
+	if len(progress.pods) != replicas {
+		glog.V(4).Infof("Waiting for %d pods of %s in TiDB %s, got %d", replicas, progress.component, key, len(progress.pods))
+		return nil
+	}
+	for _, pod := range progress.pods {
+		if !isPodReady(pod) {
+			glog.V(4).Infof("Waiting for pod %s of TiDB %s to be ready", pod.Name, key)
+			return nil
+		}
+	}
+	if err := c.checkComponentHealth(tidb, progress.component, progress.pods); err != nil {
+		glog.Infof("Waiting for %s of TiDB %s to be healthy: %v", progress.component, key, err)
+		c.workqueue.AddAfter(key, upgradeRetryInterval)
+		return nil
+	}
+
+	pod := progress.outdated[0]
+	switch {
+	case progress.component == componentPD:
+		transferred, err := c.transferPDLeader(tidb, pod, progress)
+		if err != nil {
+			return err
+		}
+		if !transferred {
+			// Wait for the new leader to be elected.
+			c.workqueue.AddAfter(key, upgradeRetryInterval)
+			return nil
+		}
+	case progress.component == componentTiKV && len(progress.pods) > 1:
+		// The pod is deleted once the region leaders are moved to the
+		// other stores, a single store could not hand them over.
+		return c.startEvictingLeader(tidb, pod)
+	}
+	return c.deleteUpgradingPod(tidb, pod, progress.component)
+}
+
+// deleteUpgradingPod deletes the pod so that the statefulset recreates it
+// with the new template, the deletion is expected until it is observed.
+func (c *Controller) deleteUpgradingPod(tidb *api.TiDB, pod *v1.Pod, component componentType) error {
+	key, err := cache.MetaNamespaceKeyFunc(tidb)
+	if err != nil {
+		return err
+	}
+	if err := c.podExpectations.ExpectDeletions(key, []string{controller.PodKey(pod)}); err != nil {
+		return err
+	}
+	c.recorder.Eventf(tidb, v1.EventTypeNormal, UpgradingPod, "Upgrading pod %s of %s", pod.Name, component)
+	if err := c.kubeclientset.CoreV1().Pods(pod.Namespace).Delete(pod.Name, nil); err != nil {
+		// The pod informer won't observe the deletion.
+		c.podExpectations.DeletionObserved(key, controller.PodKey(pod))
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete pod %s: %v", pod.Name, err)
+		}
+	}
+	return nil
+}
+
+// checkComponentHealth checks the members of the component in PD.
+func (c *Controller) checkComponentHealth(tidb *api.TiDB, component componentType, pods []*v1.Pod) error {
+	switch component {
+	case componentPD:
+		return c.checkPDHealth(tidb, pods)
+	case componentTiKV:
+		return c.checkTiKVHealth(tidb, pods)
+	}
+	return nil
+}
+
+// transferPDLeader moves the PD leadership off the pod to be upgraded. It
+// returns true if the pod is not the leader, or false if the leadership is
+// being transferred. An upgraded member is preferred to be the new leader, so
+// that the leadership does not move again in the upgrade.
+func (c *Controller) transferPDLeader(tidb *api.TiDB, pod *v1.Pod, progress *upgradeProgress) (bool, error) {
+	client := c.getPDClient(tidb)
+	leader, err := client.GetLeader()
+	if err != nil {
+		return false, fmt.Errorf("failed to get the PD leader: %v", err)
+	}
+	if leader.Name != pod.Name || len(progress.pods) == 1 {
+		// A single member could not hand over the leadership.
+		return true, nil
+	}
+
+	outdated := make(map[string]bool)
+	for _, p := range progress.outdated {
+		outdated[p.Name] = true
+	}
+	var target string
+	for _, p := range progress.pods {
+		if p.Name == pod.Name {
+			continue
+		}
+		if target == "" || (outdated[target] && !outdated[p.Name]) {
+			target = p.Name
+		}
+	}
+
+	c.recorder.Eventf(tidb, v1.EventTypeNormal, TransferringPDLeader,
+		"Transferring PD leader from %s to %s", pod.Name, target)
+	if err := client.TransferLeader(target); err != nil {
+		return false, fmt.Errorf("failed to transfer PD leader to %s: %v", target, err)
+	}
+	return false, nil
+}
+
+// isDeploymentUpgraded returns true if all the pods of the deployment run
+// the latest template.
+func isDeploymentUpgraded(deployment *apps.Deployment) bool {
+	return deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.UpdatedReplicas == deployment.Status.Replicas
+}
+
+// getPodOrdinal returns the ordinal of the pod of a statefulset, or -1 if
+// the name does not end with one.
+func getPodOrdinal(pod *v1.Pod) int {
+	i := strings.LastIndex(pod.Name, "-")
+	if i < 0 {
+		return -1
+	}
+	ordinal, err := strconv.Atoi(pod.Name[i+1:])
+	if err != nil {
+		return -1
+	}
+	return ordinal
+}
+
+// setUpgradingCondition sets the Upgrading condition to the first component
+// being upgraded, it is only set to False if the cluster has been upgraded
+// before.
+func setUpgradingCondition(tidb *api.TiDB, status *api.ClusterStatus, upgrading componentType, message string) {
+	switch {
+	case upgrading == "":
+		if getCondition(status, api.ClusterConditionUpgrading) != nil {
+			setCondition(status, api.ClusterConditionUpgrading, v1.ConditionFalse, "UpgradeCompleted", "")
+		}
+	case tidb.Spec.Paused:
+		setCondition(status, api.ClusterConditionUpgrading, v1.ConditionTrue, "UpgradePaused", message)
+	default:
+		reason := map[componentType]string{
+			componentPD:   "UpgradingPD",
+			componentTiKV: "UpgradingTiKV",
+			componentTiDB: "UpgradingTiDB",
+		}[upgrading]
+		setCondition(status, api.ClusterConditionUpgrading, v1.ConditionTrue, reason, message)
+	}
+}
@@ -0,0 +1,353 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	apps "k8s.io/api/apps/v1beta2"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+	"github.com/gaocegege/kubetidb/pkg/pdapi"
+)
+
+// newPDPods returns the pods of PD with the ordinals.
+func newPDPods(tidb *api.TiDB, ordinals ...int) []*v1.Pod {
+	pods := make([]*v1.Pod, 0, len(ordinals))
+	for _, i := range ordinals {
+		pods = append(pods, &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      genPodName(tidb, componentPD, i),
+			Namespace: tidb.Namespace,
+		}})
+	}
+	return pods
+}
+
+func TestTransferPDLeader(t *testing.T) {
+	testCases := []struct {
+		name     string
+		members  int
+		leader   int
+		pod      int
+		outdated []int
+		done     bool
+		expected string
+	}{
+		{
+			name:     "not the leader",
+			members:  3,
+			leader:   1,
+			pod:      2,
+			outdated: []int{2, 1, 0},
+			done:     true,
+			expected: "foo-pd-1",
+		},
+		{
+			name:     "single member",
+			members:  1,
+			leader:   0,
+			pod:      0,
+			outdated: []int{0},
+			done:     true,
+			expected: "foo-pd-0",
+		},
+		{
+			name:     "prefer the upgraded member",
+			members:  3,
+			leader:   1,
+			pod:      1,
+			outdated: []int{1, 0},
+			expected: "foo-pd-2",
+		},
+		{
+			name:     "all outdated",
+			members:  3,
+			leader:   2,
+			pod:      2,
+			outdated: []int{2, 1, 0},
+			expected: "foo-pd-0",
+		},
+	}
+	for _, tc := range testCases {
+		tidb := newTiDB("foo")
+		f := newFixture(t, tidb)
+		for i := 0; i < tc.members; i++ {
+			f.pd.AddMember(genPodName(tidb, componentPD, i), true)
+		}
+		leader := genPodName(tidb, componentPD, tc.leader)
+		if tc.leader != 0 {
+			if err := f.controller.getPDClient(tidb).TransferLeader(leader); err != nil {
+				t.Fatalf("%s: failed to set the leader: %v", tc.name, err)
+			}
+		}
+
+		var ordinals []int
+		for i := 0; i < tc.members; i++ {
+			ordinals = append(ordinals, i)
+		}
+		progress := &upgradeProgress{
+			component: componentPD,
+			observed:  true,
+			pods:      newPDPods(tidb, ordinals...),
+			outdated:  newPDPods(tidb, tc.outdated...),
+		}
+		pod := progress.pods[tc.pod]
+		done, err := f.controller.transferPDLeader(tidb, pod, progress)
+		if err != nil {
+			t.Errorf("%s: failed to transfer the leader: %v", tc.name, err)
+		} else if done != tc.done {
+			t.Errorf("%s: expected done %v, got %v", tc.name, tc.done, done)
+		} else if got := f.pd.Leader(); got != tc.expected {
+			t.Errorf("%s: expected leader %s, got %s", tc.name, tc.expected, got)
+		}
+		f.close()
+	}
+}
+
+func TestTransferPDLeaderUnhealthyTarget(t *testing.T) {
+	tidb := newTiDB("foo")
+	f := newFixture(t, tidb)
+	defer f.close()
+	f.pd.AddMember("foo-pd-0", true)
+	f.pd.AddMember("foo-pd-1", false)
+
+	progress := &upgradeProgress{
+		component: componentPD,
+		observed:  true,
+		pods:      newPDPods(tidb, 0, 1),
+		outdated:  newPDPods(tidb, 0),
+	}
+	if done, err := f.controller.transferPDLeader(tidb, progress.pods[0], progress); err == nil || done {
+		t.Errorf("Expected an error transferring the leader to the unhealthy member, got done %v", done)
+	}
+	if got := f.pd.Leader(); got != "foo-pd-0" {
+		t.Errorf("Expected the leader to stay foo-pd-0, got %s", got)
+	}
+}
+
+// testRevision is the revision of the latest templates of the statefulsets
+// of the running clusters.
+const testRevision = "rev-1"
+
+// newRunningCluster syncs the TiDB and returns it with the objects of the
+// running cluster: the objects created by the sync, with the statefulsets
+// having observed their templates, and the ready pods of all the components
+// running the latest templates.
+func newRunningCluster(t *testing.T, tidb *api.TiDB) (*api.TiDB, []runtime.Object) {
+	tidb, created := newCreatedCluster(t, tidb)
+	defaulted := tidb.DeepCopy()
+	api.SetObjectDefaults_TiDB(defaulted)
+
+	var objects []runtime.Object
+	for _, obj := range created {
+		if statefulSet, ok := obj.(*apps.StatefulSet); ok {
+			statefulSet = statefulSet.DeepCopy()
+			statefulSet.Status.ObservedGeneration = statefulSet.Generation
+			statefulSet.Status.Replicas = int32(getReplicas(statefulSet.Spec.Replicas))
+			statefulSet.Status.UpdateRevision = testRevision
+			obj = statefulSet
+		}
+		objects = append(objects, obj)
+	}
+	for _, component := range []componentType{componentPD, componentTiKV, componentTiDB} {
+		for i := 0; i < getComponentReplicas(defaulted, component); i++ {
+			pod := newComponentPod(tidb, component, i, true)
+			pod.Labels[apps.StatefulSetRevisionLabel] = testRevision
+			objects = append(objects, pod)
+		}
+	}
+	return tidb, objects
+}
+
+// newClusterFixture returns a fixture of the cluster whose PD members are
+// healthy and whose TiKV stores are up, the first PD member is the leader.
+func newClusterFixture(t *testing.T, tidb *api.TiDB, objects []runtime.Object) *fixture {
+	f := newFixture(t, append([]runtime.Object{tidb}, objects...)...)
+	storeID := uint64(0)
+	for _, obj := range objects {
+		pod, ok := obj.(*v1.Pod)
+		if !ok {
+			continue
+		}
+		switch pod.Labels[labelComponent] {
+		case string(componentPD):
+			f.pd.AddMember(pod.Name, true)
+		case string(componentTiKV):
+			storeID++
+			f.pd.AddStore(&pdapi.StoreInfo{Store: &pdapi.Store{
+				ID:        storeID,
+				Address:   genTiKVAddr(tidb, pod.Name),
+				StateName: pdapi.StoreStateUp,
+			}})
+		}
+	}
+	return f
+}
+
+// setOutdated labels the pods of the component with an old revision.
+func setOutdated(objects []runtime.Object, component componentType) {
+	for _, obj := range objects {
+		if pod, ok := obj.(*v1.Pod); ok && pod.Labels[labelComponent] == string(component) {
+			pod.Labels[apps.StatefulSetRevisionLabel] = "rev-0"
+		}
+	}
+}
+
+// checkTiDBPaused checks whether the rollout of the TiDB deployment is paused.
+func checkTiDBPaused(t *testing.T, f *fixture, paused bool) {
+	deployment, err := f.kubeclient.AppsV1beta2().Deployments(metav1.NamespaceDefault).Get("foo-tidb", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get the TiDB deployment: %v", err)
+	}
+	if deployment.Spec.Paused != paused {
+		t.Errorf("Expected the TiDB deployment paused %v, got %v", paused, deployment.Spec.Paused)
+	}
+}
+
+func TestSyncUpgradesPDFirst(t *testing.T) {
+	tidb := newTiDB("foo")
+	replicas := int32(3)
+	tidb.Spec.PDSpec.Replicas = &replicas
+	tidb, objects := newRunningCluster(t, tidb)
+	setOutdated(objects, componentPD)
+	setOutdated(objects, componentTiKV)
+	f := newClusterFixture(t, tidb, objects)
+	defer f.close()
+	f.sync(tidb)
+
+	// The outdated pod with the greatest ordinal is upgraded first, TiKV
+	// and TiDB wait for PD.
+	if deleted := f.deleted("pods"); !reflect.DeepEqual(deleted, []string{"foo-pd-2"}) {
+		t.Errorf("Expected pod foo-pd-2 to be upgraded, got %v", deleted)
+	}
+	if got := countEvents(f, UpgradingPod); got != 1 {
+		t.Errorf("Expected the upgrade of one pod to be reported, got %d", got)
+	}
+	checkTiDBPaused(t, f, true)
+	condition := getCondition(&f.getTiDB(tidb).Status, api.ClusterConditionUpgrading)
+	if condition == nil || condition.Status != v1.ConditionTrue || condition.Reason != "UpgradingPD" {
+		t.Errorf("Expected PD to be upgrading, got %+v", condition)
+	}
+
+	// The next pod is not deleted until the deletion is observed.
+	f.sync(tidb)
+	if deleted := f.deleted("pods"); len(deleted) != 1 {
+		t.Errorf("Expected a single pod to be upgraded at a time, got %v", deleted)
+	}
+}
+
+func TestSyncUpgradeTransfersPDLeader(t *testing.T) {
+	tidb := newTiDB("foo")
+	replicas := int32(3)
+	tidb.Spec.PDSpec.Replicas = &replicas
+	tidb, objects := newRunningCluster(t, tidb)
+	setOutdated(objects, componentPD)
+	f := newClusterFixture(t, tidb, objects)
+	defer f.close()
+	if err := f.controller.getPDClient(tidb).TransferLeader("foo-pd-2"); err != nil {
+		t.Fatalf("Failed to set the leader: %v", err)
+	}
+	f.sync(tidb)
+
+	if deleted := f.deleted("pods"); len(deleted) != 0 {
+		t.Errorf("Expected the leader not to be upgraded before the transfer, got %v", deleted)
+	}
+	if leader := f.pd.Leader(); leader == "foo-pd-2" {
+		t.Errorf("Expected the leadership to be moved off foo-pd-2")
+	}
+	if got := countEvents(f, TransferringPDLeader); got != 1 {
+		t.Errorf("Expected the transfer to be reported once, got %d", got)
+	}
+}
+
+func TestSyncUpgradeEvictsTiKVLeader(t *testing.T) {
+	tidb, objects := newRunningCluster(t, newTiDB("foo"))
+	setOutdated(objects, componentTiKV)
+	f := newClusterFixture(t, tidb, objects)
+	defer f.close()
+	f.sync(tidb)
+
+	// The region leaders are evicted from the store before its pod is
+	// deleted.
+	if deleted := f.deleted("pods"); len(deleted) != 0 {
+		t.Errorf("Expected no pod to be deleted before the eviction, got %v", deleted)
+	}
+	record := f.getTiDB(tidb).Status.TiKV.EvictingLeader
+	if record == nil || record.PodName != "foo-tikv-2" || record.StoreID != 3 {
+		t.Fatalf("Expected the eviction from store 3 of foo-tikv-2 to be recorded, got %+v", record)
+	}
+	if schedulers := f.pd.Schedulers(); !reflect.DeepEqual(schedulers, []string{pdapi.EvictLeaderSchedulerName(3)}) {
+		t.Errorf("Expected the evict leader scheduler of store 3, got %v", schedulers)
+	}
+	checkTiDBPaused(t, f, true)
+}
+
+func TestSyncUpgradeWaits(t *testing.T) {
+	testCases := []struct {
+		name   string
+		paused bool
+		// unhealthy is the PD member which is not healthy.
+		unhealthy string
+		// notReady is the PD pod which is not ready.
+		notReady int
+	}{
+		{
+			name:     "paused",
+			paused:   true,
+			notReady: -1,
+		},
+		{
+			name:      "unhealthy member",
+			unhealthy: "foo-pd-0",
+			notReady:  -1,
+		},
+		{
+			name:     "pod not ready",
+			notReady: 1,
+		},
+	}
+	for _, tc := range testCases {
+		tidb := newTiDB("foo")
+		replicas := int32(3)
+		tidb.Spec.PDSpec.Replicas = &replicas
+		tidb.Spec.Paused = tc.paused
+		tidb, objects := newRunningCluster(t, tidb)
+		setOutdated(objects, componentPD)
+		for _, obj := range objects {
+			if pod, ok := obj.(*v1.Pod); ok && pod.Name == genPodName(tidb, componentPD, tc.notReady) {
+				pod.Status.Conditions = nil
+			}
+		}
+		f := newClusterFixture(t, tidb, objects)
+		if tc.unhealthy != "" {
+			f.pd.SetHealth(tc.unhealthy, false)
+		}
+		f.sync(tidb)
+
+		if deleted := f.deleted("pods"); len(deleted) != 0 {
+			t.Errorf("%s: expected no pod to be upgraded, got %v", tc.name, deleted)
+		}
+		if leader := f.pd.Leader(); leader != "foo-pd-0" {
+			t.Errorf("%s: expected the leader to stay foo-pd-0, got %s", tc.name, leader)
+		}
+		checkTiDBPaused(t, f, true)
+		f.close()
+	}
+}
+
+func TestSyncUpgradeCompleted(t *testing.T) {
+	tidb, objects := newRunningCluster(t, newTiDB("foo"))
+	f := newClusterFixture(t, tidb, objects)
+	defer f.close()
+	f.sync(tidb)
+
+	if deleted := f.deleted("pods"); len(deleted) != 0 {
+		t.Errorf("Expected no pod to be upgraded, got %v", deleted)
+	}
+	checkTiDBPaused(t, f, false)
+	if condition := getCondition(&f.getTiDB(tidb).Status, api.ClusterConditionUpgrading); condition != nil && condition.Status != v1.ConditionFalse {
+		t.Errorf("Expected the cluster not to be upgrading, got %+v", condition)
+	}
+}
@@ -0,0 +1,454 @@
+// Package fake provides a fake PD serving the HTTP API of PD in memory, the
+// clients of PD could be tested against it.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gaocegege/kubetidb/pkg/pdapi"
+)
+
+// Server is a fake PD server, its URL is given to pdapi.NewClient.
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	members []*pdapi.Member
+	health  map[string]bool
+	leader  string
+	stores  []*pdapi.StoreInfo
+	// lastID is the ID of the last member added.
+	lastID uint64
+	// schedulers is the names of the running schedulers.
+	schedulers []string
+	// maxReplicas is the number of the replicas of each region.
+	maxReplicas    int
+	locationLabels string
+	// scheduleConfig is the keys of the schedule config which are set.
+	scheduleConfig map[string]interface{}
+	logLevel       string
+}
+
+// NewServer starts a fake PD server without members, it should be closed
+// after use.
+func NewServer() *Server {
+	s := &Server{
+		health:         make(map[string]bool),
+		maxReplicas:    3,
+		scheduleConfig: make(map[string]interface{}),
+		logLevel:       "info",
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pd/health", s.serveHealth)
+	mux.HandleFunc("/pd/api/v1/members", s.serveMembers)
+	mux.HandleFunc("/pd/api/v1/members/name/", s.serveDeleteMember)
+	mux.HandleFunc("/pd/api/v1/leader", s.serveLeader)
+	mux.HandleFunc("/pd/api/v1/leader/transfer/", s.serveTransferLeader)
+	mux.HandleFunc("/pd/api/v1/stores", s.serveStores)
+	mux.HandleFunc("/pd/api/v1/store/", s.serveStore)
+	mux.HandleFunc("/pd/api/v1/config/replicate", s.serveReplicationConfig)
+	mux.HandleFunc("/pd/api/v1/config/schedule", s.serveScheduleConfig)
+	mux.HandleFunc("/pd/api/v1/admin/log", s.serveLog)
+	mux.HandleFunc("/pd/api/v1/schedulers", s.serveSchedulers)
+	mux.HandleFunc("/pd/api/v1/schedulers/", s.serveRemoveScheduler)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// AddMember adds a member, the first member is the leader.
+func (s *Server) AddMember(name string, healthy bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastID++
+	s.members = append(s.members, &pdapi.Member{
+		Name:     name,
+		MemberID: s.lastID,
+	})
+	s.health[name] = healthy
+	if s.leader == "" {
+		s.leader = name
+	}
+}
+
+// Members returns the names of the members.
+func (s *Server) Members() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.members))
+	for _, member := range s.members {
+		names = append(names, member.Name)
+	}
+	return names
+}
+
+// SetHealth sets the health of the member.
+func (s *Server) SetHealth(name string, healthy bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.health[name] = healthy
+}
+
+// Leader returns the name of the leader.
+func (s *Server) Leader() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.leader
+}
+
+// AddStore adds a TiKV store.
+func (s *Server) AddStore(store *pdapi.StoreInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stores = append(s.stores, store)
+}
+
+// Store returns the TiKV store with the ID, it is nil if there is no such
+// store. The returned store could be modified to simulate the changes.
+func (s *Server) Store(storeID uint64) *pdapi.StoreInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.getStore(storeID)
+}
+
+// Schedulers returns the names of the running schedulers.
+func (s *Server) Schedulers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.schedulers...)
+}
+
+// SetMaxReplicas sets the number of the replicas of each region, it is 3 by
+// default.
+func (s *Server) SetMaxReplicas(maxReplicas int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxReplicas = maxReplicas
+}
+
+// LocationLabels returns the location labels of the replication config,
+// separated by commas.
+func (s *Server) LocationLabels() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.locationLabels
+}
+
+// ScheduleConfig returns the keys of the schedule config which are set, the
+// numbers are float64 as they are decoded from JSON.
+func (s *Server) ScheduleConfig() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	config := make(map[string]interface{}, len(s.scheduleConfig))
+	for k, v := range s.scheduleConfig {
+		config[k] = v
+	}
+	return config
+}
+
+// LogLevel returns the log level, it is info by default.
+func (s *Server) LogLevel() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logLevel
+}
+
+// getStore returns the store with the ID, it is nil if there is no such
+// store. The lock is held by the caller.
+func (s *Server) getStore(storeID uint64) *pdapi.StoreInfo {
+	for _, store := range s.stores {
+		if store.Store != nil && store.Store.ID == storeID {
+			return store
+		}
+	}
+	return nil
+}
+
+// getMember returns the member with the name, it is nil if there is no such
+// member. The lock is held by the caller.
+func (s *Server) getMember(name string) *pdapi.Member {
+	for _, member := range s.members {
+		if member.Name == name {
+			return member
+		}
+	}
+	return nil
+}
+
+func (s *Server) serveHealth(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	health := make([]pdapi.MemberHealth, 0, len(s.members))
+	for _, member := range s.members {
+		health = append(health, pdapi.MemberHealth{
+			Name:     member.Name,
+			MemberID: member.MemberID,
+			Health:   s.health[member.Name],
+		})
+	}
+	writeJSON(w, health)
+}
+
+func (s *Server) serveMembers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, &pdapi.MembersInfo{
+		Members: s.members,
+		Leader:  s.getMember(s.leader),
+	})
+}
+
+// serveDeleteMember deletes the member, the first member left is elected if
+// the leader is deleted.
+func (s *Server) serveDeleteMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "only DELETE is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := strings.TrimPrefix(r.URL.Path, "/pd/api/v1/members/name/")
+	for i, member := range s.members {
+		if member.Name != name {
+			continue
+		}
+		s.members = append(s.members[:i], s.members[i+1:]...)
+		delete(s.health, name)
+		if s.leader == name {
+			s.leader = ""
+			if len(s.members) > 0 {
+				s.leader = s.members[0].Name
+			}
+		}
+		writeJSON(w, "The PD member is deleted.")
+		return
+	}
+	http.Error(w, fmt.Sprintf("member %s not found", name), http.StatusNotFound)
+}
+
+func (s *Server) serveLeader(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	leader := s.getMember(s.leader)
+	if leader == nil {
+		http.Error(w, "no leader", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, leader)
+}
+
+func (s *Server) serveTransferLeader(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := strings.TrimPrefix(r.URL.Path, "/pd/api/v1/leader/transfer/")
+	if s.getMember(name) == nil || !s.health[name] {
+		http.Error(w, fmt.Sprintf("member %s is not available", name), http.StatusInternalServerError)
+		return
+	}
+	s.leader = name
+	writeJSON(w, "The transfer command is submitted.")
+}
+
+func (s *Server) serveStores(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, &pdapi.StoresInfo{
+		Count:  len(s.stores),
+		Stores: s.stores,
+	})
+}
+
+// serveStore gets or deletes the store, or sets its state. A deleted store
+// is offline, it is up to the test to make it tombstone.
+func (s *Server) serveStore(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/pd/api/v1/store/")
+	setState := strings.HasSuffix(path, "/state")
+	storeID, err := strconv.ParseUint(strings.TrimSuffix(path, "/state"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	store := s.getStore(storeID)
+	if store == nil {
+		http.Error(w, "store not found", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case setState && r.Method == http.MethodPost:
+		state := r.URL.Query().Get("state")
+		if store.Store.StateName == pdapi.StoreStateTombstone || state == pdapi.StoreStateTombstone {
+			http.Error(w, fmt.Sprintf("could not set the state of store %d to %s", storeID, state), http.StatusInternalServerError)
+			return
+		}
+		store.Store.StateName = state
+		writeJSON(w, "The store's state is updated.")
+	case !setState && r.Method == http.MethodGet:
+		writeJSON(w, store)
+	case !setState && r.Method == http.MethodDelete:
+		if store.Store.StateName == pdapi.StoreStateTombstone {
+			http.Error(w, fmt.Sprintf("store %d is tombstone", storeID), http.StatusInternalServerError)
+			return
+		}
+		store.Store.StateName = pdapi.StoreStateOffline
+		writeJSON(w, "The store is set as Offline.")
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// serveReplicationConfig gets the replication config, or sets the keys of
+// it.
+func (s *Server) serveReplicationConfig(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Method == http.MethodGet {
+		writeJSON(w, &pdapi.ReplicationConfig{
+			MaxReplicas:    s.maxReplicas,
+			LocationLabels: s.locationLabels,
+		})
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "only GET and POST are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var input struct {
+		MaxReplicas    *int    `json:"max-replicas"`
+		LocationLabels *string `json:"location-labels"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if input.MaxReplicas != nil {
+		s.maxReplicas = *input.MaxReplicas
+	}
+	if input.LocationLabels != nil {
+		s.locationLabels = *input.LocationLabels
+	}
+	writeJSON(w, nil)
+}
+
+// serveScheduleConfig gets the keys of the schedule config which are set,
+// or sets the keys of it.
+func (s *Server) serveScheduleConfig(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Method == http.MethodGet {
+		writeJSON(w, s.scheduleConfig)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "only GET and POST are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var input map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for k, v := range input {
+		s.scheduleConfig[k] = v
+	}
+	writeJSON(w, nil)
+}
+
+func (s *Server) serveLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var level string
+	if err := json.NewDecoder(r.Body).Decode(&level); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.logLevel = level
+	writeJSON(w, "The log level is updated.")
+}
+
+// serveSchedulers lists the schedulers, or adds the evict leader scheduler,
+// which moves all the leaders off the store at once.
+func (s *Server) serveSchedulers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Method == http.MethodGet {
+		writeJSON(w, s.schedulers)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "only GET and POST are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var input struct {
+		Name    string `json:"name"`
+		StoreID uint64 `json:"store_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if input.Name != "evict-leader-scheduler" {
+		http.Error(w, fmt.Sprintf("unsupported scheduler %s", input.Name), http.StatusInternalServerError)
+		return
+	}
+	store := s.getStore(input.StoreID)
+	if store == nil {
+		http.Error(w, "store not found", http.StatusInternalServerError)
+		return
+	}
+	name := pdapi.EvictLeaderSchedulerName(input.StoreID)
+	for _, scheduler := range s.schedulers {
+		if scheduler == name {
+			http.Error(w, "scheduler existed", http.StatusInternalServerError)
+			return
+		}
+	}
+	s.schedulers = append(s.schedulers, name)
+	if store.Status != nil {
+		store.Status.LeaderCount = 0
+	}
+	writeJSON(w, "The scheduler is created.")
+}
+
+func (s *Server) serveRemoveScheduler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "only DELETE is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := strings.TrimPrefix(r.URL.Path, "/pd/api/v1/schedulers/")
+	for i, scheduler := range s.schedulers {
+		if scheduler == name {
+			s.schedulers = append(s.schedulers[:i], s.schedulers[i+1:]...)
+			writeJSON(w, "The scheduler is removed.")
+			return
+		}
+	}
+	http.Error(w, "scheduler not found", http.StatusInternalServerError)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
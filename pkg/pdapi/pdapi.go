@@ -0,0 +1,308 @@
+// Package pdapi is a client of the HTTP API of PD, which is used to manage
+// the members of a TiDB cluster.
+package pdapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the timeout of the requests, they fail fast so that
+	// an unreachable PD does not block the callers.
+	DefaultTimeout = 5 * time.Second
+
+	healthPath         = "/pd/health"
+	membersPath        = "/pd/api/v1/members"
+	leaderPath         = "/pd/api/v1/leader"
+	leaderTransferPath = "/pd/api/v1/leader/transfer"
+	storesPath         = "/pd/api/v1/stores"
+	storePath          = "/pd/api/v1/store"
+	schedulersPath     = "/pd/api/v1/schedulers"
+	replicationPath    = "/pd/api/v1/config/replicate"
+	schedulePath       = "/pd/api/v1/config/schedule"
+	logPath            = "/pd/api/v1/admin/log"
+
+	evictLeaderSchedulerName = "evict-leader-scheduler"
+)
+
+// The states of the TiKV stores.
+const (
+	// StoreStateUp is the state of the stores serving requests.
+	StoreStateUp = "Up"
+	// StoreStateDown is the state of the stores without heartbeats for
+	// longer than max-store-down-time.
+	StoreStateDown = "Down"
+	// StoreStateOffline is the state of the stores being removed, their
+	// regions are migrated to the other stores.
+	StoreStateOffline = "Offline"
+	// StoreStateTombstone is the state of the removed stores, they have no
+	// region left.
+	StoreStateTombstone = "Tombstone"
+)
+
+// MemberHealth is the health of a PD member.
+type MemberHealth struct {
+	Name       string   `json:"name"`
+	MemberID   uint64   `json:"member_id"`
+	ClientURLs []string `json:"client_urls"`
+	Health     bool     `json:"health"`
+}
+
+// Member is a PD member.
+type Member struct {
+	Name       string   `json:"name"`
+	MemberID   uint64   `json:"member_id"`
+	PeerURLs   []string `json:"peer_urls"`
+	ClientURLs []string `json:"client_urls"`
+}
+
+// MembersInfo is the members of PD and the leader.
+type MembersInfo struct {
+	Members []*Member `json:"members"`
+	Leader  *Member   `json:"leader"`
+}
+
+// Store is a TiKV store registered in PD.
+type Store struct {
+	ID        uint64 `json:"id"`
+	Address   string `json:"address"`
+	StateName string `json:"state_name"`
+}
+
+// StoreStatus is the statistics of a TiKV store.
+type StoreStatus struct {
+	LeaderCount int `json:"leader_count"`
+	RegionCount int `json:"region_count"`
+}
+
+// StoreInfo is a TiKV store and its statistics.
+type StoreInfo struct {
+	Store  *Store       `json:"store"`
+	Status *StoreStatus `json:"status"`
+}
+
+// StoresInfo is the TiKV stores registered in PD.
+type StoresInfo struct {
+	Count  int          `json:"count"`
+	Stores []*StoreInfo `json:"stores"`
+}
+
+// ReplicationConfig is the replication config of PD.
+type ReplicationConfig struct {
+	// MaxReplicas is the number of the replicas of each region.
+	MaxReplicas int `json:"max-replicas"`
+	// LocationLabels is the labels of the stores used to isolate the
+	// replicas, separated by commas.
+	LocationLabels string `json:"location-labels"`
+}
+
+// Client is the client of the HTTP API of a PD cluster.
+type Client interface {
+	// GetHealth returns the health of all the members.
+	GetHealth() ([]MemberHealth, error)
+	// GetMembers returns all the members and the leader.
+	GetMembers() (*MembersInfo, error)
+	// GetLeader returns the leader.
+	GetLeader() (*Member, error)
+	// DeleteMember deletes the member with the name, it stops serving and
+	// could only join PD again with empty data.
+	DeleteMember(name string) error
+	// TransferLeader transfers the leadership to the member with the name.
+	TransferLeader(name string) error
+	// GetStores returns all the TiKV stores.
+	GetStores() (*StoresInfo, error)
+	// GetStore returns the TiKV store with the ID.
+	GetStore(storeID uint64) (*StoreInfo, error)
+	// DeleteStore marks the TiKV store with the ID offline, PD migrates its
+	// regions and then marks it tombstone.
+	DeleteStore(storeID uint64) error
+	// SetStoreState sets the state of the TiKV store with the ID, e.g. an
+	// offline store is brought up again.
+	SetStoreState(storeID uint64, state string) error
+	// GetReplicationConfig returns the replication config.
+	GetReplicationConfig() (*ReplicationConfig, error)
+	// SetReplicationConfig sets the keys of the replication config, e.g.
+	// max-replicas, the other keys are kept.
+	SetReplicationConfig(config map[string]interface{}) error
+	// SetScheduleConfig sets the keys of the schedule config, e.g.
+	// leader-schedule-limit, the other keys are kept.
+	SetScheduleConfig(config map[string]interface{}) error
+	// SetLogLevel sets the log level of the member serving the request.
+	SetLogLevel(level string) error
+	// GetSchedulers returns the names of the running schedulers.
+	GetSchedulers() ([]string, error)
+	// AddEvictLeaderScheduler adds the scheduler evicting all the region
+	// leaders from the store.
+	AddEvictLeaderScheduler(storeID uint64) error
+	// RemoveEvictLeaderScheduler removes the scheduler evicting the region
+	// leaders from the store.
+	RemoveEvictLeaderScheduler(storeID uint64) error
+}
+
+// EvictLeaderSchedulerName returns the name of the scheduler evicting the
+// region leaders from the store.
+func EvictLeaderSchedulerName(storeID uint64) string {
+	return fmt.Sprintf("%s-%d", evictLeaderSchedulerName, storeID)
+}
+
+type client struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewClient returns the client of the PD cluster serving at the URL, e.g.
+// http://foo-pd.default.svc:2379.
+func NewClient(url string, timeout time.Duration) Client {
+	return &client{
+		url:        strings.TrimSuffix(url, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *client) GetHealth() ([]MemberHealth, error) {
+	var health []MemberHealth
+	if err := c.get(healthPath, &health); err != nil {
+		return nil, err
+	}
+	return health, nil
+}
+
+func (c *client) GetMembers() (*MembersInfo, error) {
+	members := &MembersInfo{}
+	if err := c.get(membersPath, members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (c *client) GetLeader() (*Member, error) {
+	leader := &Member{}
+	if err := c.get(leaderPath, leader); err != nil {
+		return nil, err
+	}
+	return leader, nil
+}
+
+func (c *client) DeleteMember(name string) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("%s/name/%s", membersPath, name), nil, nil)
+}
+
+func (c *client) TransferLeader(name string) error {
+	return c.do(http.MethodPost, fmt.Sprintf("%s/%s", leaderTransferPath, name), nil, nil)
+}
+
+func (c *client) GetStores() (*StoresInfo, error) {
+	stores := &StoresInfo{}
+	if err := c.get(storesPath, stores); err != nil {
+		return nil, err
+	}
+	return stores, nil
+}
+
+func (c *client) GetStore(storeID uint64) (*StoreInfo, error) {
+	store := &StoreInfo{}
+	if err := c.get(fmt.Sprintf("%s/%d", storePath, storeID), store); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (c *client) DeleteStore(storeID uint64) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("%s/%d", storePath, storeID), nil, nil)
+}
+
+func (c *client) SetStoreState(storeID uint64, state string) error {
+	return c.do(http.MethodPost, fmt.Sprintf("%s/%d/state?state=%s", storePath, storeID, url.QueryEscape(state)), nil, nil)
+}
+
+func (c *client) GetReplicationConfig() (*ReplicationConfig, error) {
+	config := &ReplicationConfig{}
+	if err := c.get(replicationPath, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *client) SetReplicationConfig(config map[string]interface{}) error {
+	return c.do(http.MethodPost, replicationPath, config, nil)
+}
+
+func (c *client) SetScheduleConfig(config map[string]interface{}) error {
+	return c.do(http.MethodPost, schedulePath, config, nil)
+}
+
+func (c *client) SetLogLevel(level string) error {
+	return c.do(http.MethodPost, logPath, level, nil)
+}
+
+func (c *client) GetSchedulers() ([]string, error) {
+	var schedulers []string
+	if err := c.get(schedulersPath, &schedulers); err != nil {
+		return nil, err
+	}
+	return schedulers, nil
+}
+
+func (c *client) AddEvictLeaderScheduler(storeID uint64) error {
+	return c.do(http.MethodPost, schedulersPath, map[string]interface{}{
+		"name":     evictLeaderSchedulerName,
+		"store_id": storeID,
+	}, nil)
+}
+
+func (c *client) RemoveEvictLeaderScheduler(storeID uint64) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("%s/%s", schedulersPath, EvictLeaderSchedulerName(storeID)), nil, nil)
+}
+
+// get gets the path and decodes the JSON response into v.
+func (c *client) get(path string, v interface{}) error {
+	return c.do(http.MethodGet, path, nil, v)
+}
+
+// do sends the request with the JSON body if it is not nil, and the response
+// is decoded into v if it is not nil.
+func (c *client) do(method, path string, body, v interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.url+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s returned %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode the response of %s %s: %v", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package pdapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gaocegege/kubetidb/pkg/pdapi"
+	"github.com/gaocegege/kubetidb/pkg/pdapi/fake"
+)
+
+// newClient returns a client of the fake PD with the members, the first one
+// is the leader.
+func newClient(members ...string) (*fake.Server, pdapi.Client) {
+	server := fake.NewServer()
+	for _, member := range members {
+		server.AddMember(member, true)
+	}
+	return server, pdapi.NewClient(server.URL, pdapi.DefaultTimeout)
+}
+
+func TestGetMembers(t *testing.T) {
+	server, client := newClient("foo-pd-0", "foo-pd-1", "foo-pd-2")
+	defer server.Close()
+	server.SetHealth("foo-pd-1", false)
+
+	health, err := client.GetHealth()
+	if err != nil {
+		t.Fatalf("Failed to get the health: %v", err)
+	}
+	got := make(map[string]bool)
+	for _, member := range health {
+		got[member.Name] = member.Health
+	}
+	expected := map[string]bool{"foo-pd-0": true, "foo-pd-1": false, "foo-pd-2": true}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected health %v, got %v", expected, got)
+	}
+
+	members, err := client.GetMembers()
+	if err != nil {
+		t.Fatalf("Failed to get the members: %v", err)
+	}
+	if len(members.Members) != 3 {
+		t.Errorf("Expected 3 members, got %d", len(members.Members))
+	}
+	if members.Leader == nil || members.Leader.Name != "foo-pd-0" {
+		t.Errorf("Expected leader foo-pd-0, got %+v", members.Leader)
+	}
+}
+
+func TestLeader(t *testing.T) {
+	server, client := newClient("foo-pd-0", "foo-pd-1")
+	defer server.Close()
+
+	leader, err := client.GetLeader()
+	if err != nil {
+		t.Fatalf("Failed to get the leader: %v", err)
+	}
+	if leader.Name != "foo-pd-0" || leader.MemberID == 0 {
+		t.Errorf("Expected leader foo-pd-0, got %+v", leader)
+	}
+
+	if err := client.TransferLeader("foo-pd-1"); err != nil {
+		t.Fatalf("Failed to transfer the leader: %v", err)
+	}
+	if server.Leader() != "foo-pd-1" {
+		t.Errorf("Expected leader foo-pd-1, got %s", server.Leader())
+	}
+	if err := client.TransferLeader("foo-pd-2"); err == nil {
+		t.Errorf("Expected an error transferring the leader to an unknown member")
+	}
+}
+
+func TestDeleteMember(t *testing.T) {
+	server, client := newClient("foo-pd-0", "foo-pd-1", "foo-pd-2")
+	defer server.Close()
+
+	if err := client.DeleteMember("foo-pd-0"); err != nil {
+		t.Fatalf("Failed to delete the member: %v", err)
+	}
+	if members := server.Members(); !reflect.DeepEqual(members, []string{"foo-pd-1", "foo-pd-2"}) {
+		t.Errorf("Expected members foo-pd-1 and foo-pd-2, got %v", members)
+	}
+	if server.Leader() != "foo-pd-1" {
+		t.Errorf("Expected a new leader to be elected, got %q", server.Leader())
+	}
+	if err := client.DeleteMember("foo-pd-0"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected a not found error deleting the member again, got %v", err)
+	}
+}
+
+func TestStores(t *testing.T) {
+	server, client := newClient("foo-pd-0")
+	defer server.Close()
+	server.AddStore(&pdapi.StoreInfo{
+		Store:  &pdapi.Store{ID: 1, Address: "foo-tikv-0:20160", StateName: pdapi.StoreStateUp},
+		Status: &pdapi.StoreStatus{RegionCount: 10},
+	})
+	server.AddStore(&pdapi.StoreInfo{
+		Store: &pdapi.Store{ID: 2, Address: "foo-tikv-1:20160", StateName: pdapi.StoreStateUp},
+	})
+
+	stores, err := client.GetStores()
+	if err != nil {
+		t.Fatalf("Failed to get the stores: %v", err)
+	}
+	if stores.Count != 2 || len(stores.Stores) != 2 {
+		t.Errorf("Expected 2 stores, got %+v", stores)
+	}
+
+	store, err := client.GetStore(1)
+	if err != nil {
+		t.Fatalf("Failed to get store 1: %v", err)
+	}
+	if store.Store.Address != "foo-tikv-0:20160" || store.Status.RegionCount != 10 {
+		t.Errorf("Expected store 1 with 10 regions, got %+v", store)
+	}
+
+	if err := client.DeleteStore(1); err != nil {
+		t.Fatalf("Failed to delete store 1: %v", err)
+	}
+	if state := server.Store(1).Store.StateName; state != pdapi.StoreStateOffline {
+		t.Errorf("Expected store 1 to be offline, got %s", state)
+	}
+	if err := client.SetStoreState(1, pdapi.StoreStateUp); err != nil {
+		t.Fatalf("Failed to bring up store 1: %v", err)
+	}
+	if state := server.Store(1).Store.StateName; state != pdapi.StoreStateUp {
+		t.Errorf("Expected store 1 to be up, got %s", state)
+	}
+	if _, err := client.GetStore(3); err == nil {
+		t.Errorf("Expected an error getting an unknown store")
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	// PD has no leader without the quorum.
+	server, client := newClient()
+	defer server.Close()
+
+	_, err := client.GetLeader()
+	if err == nil {
+		t.Fatalf("Expected an error without the leader")
+	}
+	expected := "GET /pd/api/v1/leader returned 500 Internal Server Error: no leader"
+	if err.Error() != expected {
+		t.Errorf("Expected error %q, got %q", expected, err.Error())
+	}
+}
+
+func TestInvalidResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not json"))
+	}))
+	defer server.Close()
+
+	_, err := pdapi.NewClient(server.URL, pdapi.DefaultTimeout).GetMembers()
+	if err == nil || !strings.Contains(err.Error(), "failed to decode the response of GET /pd/api/v1/members") {
+		t.Errorf("Expected a decode error, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+// Package fake provides a fake TiDB server serving the HTTP API of the
+// status port in memory, the clients of TiDB could be tested against it.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// logLevels is the log levels accepted by TiDB.
+var logLevels = map[string]bool{
+	"debug": true,
+	"info":  true,
+	"warn":  true,
+	"error": true,
+	"fatal": true,
+}
+
+// Server is a fake TiDB server, its URL is given to tidbapi.NewClient.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	logLevel string
+}
+
+// NewServer starts a fake TiDB server, it should be closed after use.
+func NewServer() *Server {
+	s := &Server{logLevel: "info"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/settings", s.serveSettings)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// LogLevel returns the log level, it is info by default.
+func (s *Server) LogLevel() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logLevel
+}
+
+// serveSettings gets the settings, or sets them from the form.
+func (s *Server) serveSettings(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if level := r.Form.Get("log_level"); level != "" {
+			if !logLevels[level] {
+				http.Error(w, fmt.Sprintf("invalid log level %s", level), http.StatusBadRequest)
+				return
+			}
+			s.logLevel = level
+		}
+	} else if r.Method != http.MethodGet {
+		http.Error(w, "only GET and POST are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"log": map[string]string{"level": s.logLevel},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,63 @@
+// Package tidbapi is a client of the HTTP API served at the status port of a
+// TiDB server, which is used to change the settings of the server online.
+package tidbapi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the timeout of the requests, they fail fast so that
+	// an unreachable server does not block the callers.
+	DefaultTimeout = 5 * time.Second
+
+	settingsPath = "/settings"
+)
+
+// Client is the client of the HTTP API of a TiDB server.
+type Client interface {
+	// SetLogLevel sets the log level of the server.
+	SetLogLevel(level string) error
+}
+
+type client struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewClient returns the client of the TiDB server serving the status port at
+// the URL, e.g. http://10.0.0.1:10080.
+func NewClient(url string, timeout time.Duration) Client {
+	return &client{
+		url:        strings.TrimSuffix(url, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *client) SetLogLevel(level string) error {
+	return c.postForm(settingsPath, url.Values{"log_level": {level}})
+}
+
+// postForm posts the form to the path, the response is ignored unless the
+// request fails.
+func (c *client) postForm(path string, form url.Values) error {
+	resp, err := c.httpClient.PostForm(c.url+path, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s returned %s: %s", http.MethodPost, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
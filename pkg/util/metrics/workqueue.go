@@ -0,0 +1,77 @@
+// Package metrics registers the Prometheus metrics of the workqueues. It is
+// imported for the side effect, before any workqueue is created:
+//
+//	import _ "github.com/gaocegege/kubetidb/pkg/util/metrics"
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	namespace = "kubetidb"
+	subsystem = "workqueue"
+)
+
+var (
+	depth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "depth",
+		Help:      "Current depth of the workqueue.",
+	}, []string{"name"})
+	adds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "adds_total",
+		Help:      "Total number of adds handled by the workqueue.",
+	}, []string{"name"})
+	latency = prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "queue_latency_microseconds",
+		Help:      "How long an item stays in the workqueue before being requested.",
+	}, []string{"name"})
+	workDuration = prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "work_duration_microseconds",
+		Help:      "How long processing an item from the workqueue takes.",
+	}, []string{"name"})
+	retries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "retries_total",
+		Help:      "Total number of retries handled by the workqueue.",
+	}, []string{"name"})
+)
+
+func init() {
+	prometheus.MustRegister(depth, adds, latency, workDuration, retries)
+	workqueue.SetProvider(prometheusProvider{})
+}
+
+// prometheusProvider implements workqueue.MetricsProvider, the metrics of
+// all the workqueues are labelled with the name of the workqueue.
+type prometheusProvider struct{}
+
+func (prometheusProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return depth.WithLabelValues(name)
+}
+
+func (prometheusProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return adds.WithLabelValues(name)
+}
+
+func (prometheusProvider) NewLatencyMetric(name string) workqueue.SummaryMetric {
+	return latency.WithLabelValues(name)
+}
+
+func (prometheusProvider) NewWorkDurationMetric(name string) workqueue.SummaryMetric {
+	return workDuration.WithLabelValues(name)
+}
+
+func (prometheusProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return retries.WithLabelValues(name)
+}
@@ -0,0 +1,196 @@
+package toml
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// section is a table of a TOML document, the root table has no header.
+type section struct {
+	header string
+	// name is the name of the table in the header, or empty for the root
+	// table and the arrays of tables which are never merged.
+	name  string
+	lines []string
+	keys  []string
+}
+
+// Merge returns the TOML document of base with the keys of extra added. The
+// tables defined in both are merged into one, since a table could not be
+// defined twice. It returns an error if a key is set in both. Only the
+// headers and the keys are parsed, the values are kept as they are.
+func Merge(base, extra []byte) ([]byte, error) {
+	baseSections, err := splitSections(base)
+	if err != nil {
+		return nil, err
+	}
+	extraSections, err := splitSections(extra)
+	if err != nil {
+		return nil, err
+	}
+
+	sections := baseSections
+	for _, extraSection := range extraSections {
+		merged := false
+		for _, s := range sections {
+			if s.name != extraSection.name || isArrayOfTables(s) || isArrayOfTables(extraSection) {
+				continue
+			}
+			for _, key := range extraSection.keys {
+				for _, existing := range s.keys {
+					if key == existing {
+						return nil, fmt.Errorf("toml: key %s is set twice", joinKey(s.name, key))
+					}
+				}
+			}
+			s.keys = append(s.keys, extraSection.keys...)
+			s.lines = append(trimBlankLines(s.lines), extraSection.lines...)
+			merged = true
+			break
+		}
+		if !merged {
+			sections = append(sections, extraSection)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, s := range sections {
+		lines := trimBlankLines(s.lines)
+		if s.header == "" && len(lines) == 0 {
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		if s.header != "" {
+			buf.WriteString(s.header + "\n")
+		}
+		for _, line := range lines {
+			buf.WriteString(line + "\n")
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// splitSections splits the document into the root table and the tables
+// after it.
+func splitSections(data []byte) ([]*section, error) {
+	root := &section{}
+	sections := []*section{root}
+	current := root
+
+	var multiline string
+	depth := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if multiline != "" || depth > 0 {
+			// The line continues the value of the previous key.
+			multiline, depth = scanValue(line, multiline, depth)
+			current.lines = append(current.lines, line)
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			current.lines = append(current.lines, line)
+		case strings.HasPrefix(trimmed, "[["):
+			current = &section{header: trimmed}
+			sections = append(sections, current)
+		case strings.HasPrefix(trimmed, "["):
+			end := strings.Index(trimmed, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("toml: invalid table header %q", trimmed)
+			}
+			name := normalizeKey(trimmed[1:end])
+			current = &section{header: "[" + name + "]", name: name}
+			for _, s := range sections {
+				if s.name == name && s.name != "" {
+					return nil, fmt.Errorf("toml: table %s is defined twice", name)
+				}
+			}
+			sections = append(sections, current)
+		default:
+			eq := strings.Index(trimmed, "=")
+			if eq < 0 {
+				return nil, fmt.Errorf("toml: invalid line %q", trimmed)
+			}
+			key := normalizeKey(trimmed[:eq])
+			for _, existing := range current.keys {
+				if key == existing {
+					return nil, fmt.Errorf("toml: key %s is set twice", joinKey(current.name, key))
+				}
+			}
+			current.keys = append(current.keys, key)
+			current.lines = append(current.lines, line)
+			multiline, depth = scanValue(trimmed[eq+1:], "", 0)
+		}
+	}
+	return sections, nil
+}
+
+// scanValue scans the value, or a line of it, and returns the delimiter of
+// the multi-line string and the depth of the array which are still open.
+func scanValue(value, multiline string, depth int) (string, int) {
+	for i := 0; i < len(value); i++ {
+		if multiline != "" {
+			if strings.HasPrefix(value[i:], multiline) {
+				i += len(multiline) - 1
+				multiline = ""
+			} else if value[i] == '\\' && multiline == `"""` {
+				i++
+			}
+			continue
+		}
+		switch c := value[i]; {
+		case strings.HasPrefix(value[i:], `"""`) || strings.HasPrefix(value[i:], `'''`):
+			multiline = value[i : i+3]
+			i += 2
+		case c == '"' || c == '\'':
+			// Skip the single-line string.
+			for i++; i < len(value) && value[i] != c; i++ {
+				if value[i] == '\\' && c == '"' {
+					i++
+				}
+			}
+		case c == '#':
+			return multiline, depth
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		}
+	}
+	return multiline, depth
+}
+
+// normalizeKey removes the whitespace around the key and its dotted parts.
+func normalizeKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return strings.Join(parts, ".")
+}
+
+func isArrayOfTables(s *section) bool {
+	return s.header != "" && s.name == ""
+}
+
+func joinKey(table, key string) string {
+	if table == "" {
+		return key
+	}
+	return table + "." + key
+}
+
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,215 @@
+// Package toml encodes the config structs into TOML, the config format of
+// PD, TiKV and TiDB. Only the types used by the configs are supported, i.e.
+// strings, booleans, numbers, slices of them and structs as tables. The
+// fields are encoded with the names in the toml tags, the fields without the
+// tags are skipped.
+package toml
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Marshal returns the TOML encoding of the struct. The nil pointers and the
+// empty non-pointer fields with the omitempty option are omitted, and so are
+// the tables without any key.
+func Marshal(v interface{}) ([]byte, error) {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("toml: unsupported type %T, expected a struct", v)
+	}
+	var buf bytes.Buffer
+	if err := encodeTable(&buf, nil, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeTable writes the keys of the table, and then the sub tables with
+// their headers since the keys after a header belong to that table.
+func encodeTable(buf *bytes.Buffer, path []string, value reflect.Value) error {
+	type table struct {
+		name  string
+		value reflect.Value
+	}
+	var tables []table
+
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		name, omitEmpty := parseTag(field.Tag.Get("toml"))
+		if name == "" || field.PkgPath != "" {
+			continue
+		}
+		fieldValue := value.Field(i)
+		if fieldValue.Kind() == reflect.Ptr {
+			if fieldValue.IsNil() {
+				continue
+			}
+			// The zero value pointed to is given explicitly.
+			fieldValue, omitEmpty = fieldValue.Elem(), false
+		}
+		if fieldValue.Kind() == reflect.Struct {
+			tables = append(tables, table{name, fieldValue})
+			continue
+		}
+		if omitEmpty && isEmpty(fieldValue) {
+			continue
+		}
+		encoded, err := encodeValue(fieldValue)
+		if err != nil {
+			return fmt.Errorf("toml: field %s: %v", field.Name, err)
+		}
+		fmt.Fprintf(buf, "%s = %s\n", quoteKey(name), encoded)
+	}
+
+	for _, t := range tables {
+		var sub bytes.Buffer
+		subPath := append(append([]string(nil), path...), t.name)
+		if err := encodeTable(&sub, subPath, t.value); err != nil {
+			return err
+		}
+		if sub.Len() == 0 {
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		if !bytes.HasPrefix(sub.Bytes(), []byte("[")) {
+			// The table has keys of its own, otherwise the headers of
+			// the sub tables define it implicitly.
+			keys := make([]string, 0, len(subPath))
+			for _, key := range subPath {
+				keys = append(keys, quoteKey(key))
+			}
+			fmt.Fprintf(buf, "[%s]\n", strings.Join(keys, "."))
+		}
+		buf.Write(sub.Bytes())
+	}
+	return nil
+}
+
+// Flatten returns the keys of the struct with their values, the keys of the
+// tables are prefixed with the table names, e.g. log.level. The keys are
+// omitted the same as Marshal does.
+func Flatten(v interface{}) (map[string]interface{}, error) {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("toml: unsupported type %T, expected a struct", v)
+	}
+	keys := make(map[string]interface{})
+	flattenTable(keys, "", value)
+	return keys, nil
+}
+
+func flattenTable(keys map[string]interface{}, prefix string, value reflect.Value) {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		name, omitEmpty := parseTag(field.Tag.Get("toml"))
+		if name == "" || field.PkgPath != "" {
+			continue
+		}
+		fieldValue := value.Field(i)
+		if fieldValue.Kind() == reflect.Ptr {
+			if fieldValue.IsNil() {
+				continue
+			}
+			// The zero value pointed to is given explicitly.
+			fieldValue, omitEmpty = fieldValue.Elem(), false
+		}
+		if fieldValue.Kind() == reflect.Struct {
+			flattenTable(keys, prefix+name+".", fieldValue)
+			continue
+		}
+		if omitEmpty && isEmpty(fieldValue) {
+			continue
+		}
+		keys[prefix+name] = fieldValue.Interface()
+	}
+}
+
+// encodeValue returns the TOML encoding of the value of a key.
+func encodeValue(value reflect.Value) (string, error) {
+	switch value.Kind() {
+	case reflect.String:
+		return quoteString(value.String()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64), nil
+	case reflect.Slice, reflect.Array:
+		items := make([]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			item, err := encodeValue(reflect.Indirect(value.Index(i)))
+			if err != nil {
+				return "", err
+			}
+			items = append(items, item)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	}
+	return "", fmt.Errorf("unsupported kind %s", value.Kind())
+}
+
+// parseTag returns the name and whether the omitempty option is given.
+func parseTag(tag string) (string, bool) {
+	parts := strings.Split(tag, ",")
+	if parts[0] == "-" {
+		return "", false
+	}
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			return parts[0], true
+		}
+	}
+	return parts[0], false
+}
+
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array, reflect.String, reflect.Map:
+		return value.Len() == 0
+	}
+	return reflect.DeepEqual(value.Interface(), reflect.Zero(value.Type()).Interface())
+}
+
+// quoteKey returns the key as it is if it is a bare key, or quoted.
+func quoteKey(key string) string {
+	for _, r := range key {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return quoteString(key)
+		}
+	}
+	return key
+}
+
+// quoteString returns the TOML basic string of s.
+func quoteString(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&buf, `\u%04X`, r)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
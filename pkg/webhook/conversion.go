@@ -0,0 +1,118 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// ConvertPath is the path of the conversion webhook of TiDB clusters.
+	ConvertPath = "/convert"
+)
+
+// The types below are the ConversionReview of apiextensions.k8s.io/v1beta1,
+// which are not vendored.
+
+// ConversionReview describes a conversion request and response.
+type ConversionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *ConversionRequest  `json:"request,omitempty"`
+	Response        *ConversionResponse `json:"response,omitempty"`
+}
+
+// ConversionRequest describes the conversion request parameters.
+type ConversionRequest struct {
+	UID               types.UID              `json:"uid"`
+	DesiredAPIVersion string                 `json:"desiredAPIVersion"`
+	Objects           []runtime.RawExtension `json:"objects"`
+}
+
+// ConversionResponse describes a conversion response.
+type ConversionResponse struct {
+	UID              types.UID              `json:"uid"`
+	ConvertedObjects []runtime.RawExtension `json:"convertedObjects"`
+	Result           metav1.Status          `json:"result"`
+}
+
+// serveConvert converts the TiDBs in the conversion review to the desired
+// version.
+func serveConvert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	review := &ConversionReview{}
+	if err := json.Unmarshal(body, review); err != nil || review.Request == nil {
+		http.Error(w, fmt.Sprintf("failed to decode the conversion review: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	review.Response = convert(review.Request)
+	review.Request = nil
+	resp, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(resp); err != nil {
+		glog.Errorf("Failed to write the conversion review: %v", err)
+	}
+}
+
+// convert converts all the objects in the request, the request fails as a
+// whole if any of them could not be converted.
+func convert(req *ConversionRequest) *ConversionResponse {
+	resp := &ConversionResponse{UID: req.UID}
+	gv, err := schema.ParseGroupVersion(req.DesiredAPIVersion)
+	if err != nil {
+		resp.Result = failed(err)
+		return resp
+	}
+
+	for _, object := range req.Objects {
+		converted, err := convertObject(object.Raw, gv)
+		if err != nil {
+			resp.ConvertedObjects = nil
+			resp.Result = failed(err)
+			return resp
+		}
+		resp.ConvertedObjects = append(resp.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+	resp.Result = metav1.Status{Status: metav1.StatusSuccess}
+	return resp
+}
+
+// convertObject converts the object to the given version. The defaults are
+// not set, the stored objects are returned as they are.
+func convertObject(raw []byte, gv schema.GroupVersion) ([]byte, error) {
+	obj, _, err := codecs.UniversalDeserializer().Decode(raw, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the object: %v", err)
+	}
+	converted, err := scheme.ConvertToVersion(obj, gv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert the object to %s: %v", gv, err)
+	}
+	return json.Marshal(converted)
+}
+
+func failed(err error) metav1.Status {
+	return metav1.Status{
+		Status:  metav1.StatusFailure,
+		Message: err.Error(),
+	}
+}
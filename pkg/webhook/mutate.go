@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+const (
+	// MutatePath is the path of the mutating admission webhook of TiDB
+	// clusters, which sets the defaults.
+	MutatePath = "/mutate"
+
+	patchTypeJSONPatch = "JSONPatch"
+)
+
+// patchOperation is an operation of a JSON patch.
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// serveMutate writes back the patch setting the defaults of the TiDB in the
+// admission review.
+func serveMutate(w http.ResponseWriter, r *http.Request) {
+	serveReview(w, r, mutate)
+}
+
+// mutate returns the patch setting the defaults of the TiDB in the request.
+// The defaults are set in the version of the TiDB, they are persisted with
+// the TiDB so that the controller does not update it.
+func mutate(req *AdmissionRequest) *AdmissionResponse {
+	resp := &AdmissionResponse{UID: req.UID, Allowed: true}
+	if req.Kind.Group != api.GroupName || req.Kind.Kind != api.ResourceKind {
+		return resp
+	}
+	if req.Operation != "CREATE" && req.Operation != "UPDATE" {
+		return resp
+	}
+
+	patch, err := genDefaultsPatch(req.Object.Raw)
+	if err != nil {
+		return denied(req.UID, metav1.StatusReasonBadRequest, err.Error())
+	}
+	if len(patch) == 0 {
+		return resp
+	}
+	if resp.Patch, err = json.Marshal(patch); err != nil {
+		return denied(req.UID, metav1.StatusReasonInternalError, err.Error())
+	}
+	patchType := patchTypeJSONPatch
+	resp.PatchType = &patchType
+	return resp
+}
+
+// genDefaultsPatch returns the JSON patch adding the defaults to the spec of
+// the TiDB. The fields which are not known to the types are kept.
+func genDefaultsPatch(raw []byte) ([]patchOperation, error) {
+	obj, _, err := codecs.UniversalDeserializer().Decode(raw, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the TiDB: %v", err)
+	}
+	scheme.Default(obj)
+	defaulted, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var original, desired map[string]interface{}
+	if err := json.Unmarshal(raw, &original); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(defaulted, &desired); err != nil {
+		return nil, err
+	}
+	return genPatch("/spec", original["spec"], desired["spec"]), nil
+}
+
+// genPatch returns the operations which add the desired values missing or
+// different in the original ones. Nothing is removed.
+func genPatch(path string, original, desired interface{}) []patchOperation {
+	originalMap, ok := original.(map[string]interface{})
+	desiredMap, desiredOk := desired.(map[string]interface{})
+	if ok && desiredOk {
+		keys := make([]string, 0, len(desiredMap))
+		for k := range desiredMap {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		var ops []patchOperation
+		for _, k := range keys {
+			ops = append(ops, genPatch(path+"/"+escapePointer(k), originalMap[k], desiredMap[k])...)
+		}
+		return ops
+	}
+	if desired == nil || reflect.DeepEqual(original, desired) {
+		return nil
+	}
+	return []patchOperation{{Op: "add", Path: path, Value: desired}}
+}
+
+// escapePointer escapes the key as a reference token of a JSON pointer.
+func escapePointer(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+// getPatchValue returns the value added at the path by the patch.
+func getPatchValue(patch []patchOperation, path string) (interface{}, bool) {
+	for _, op := range patch {
+		if op.Path == path {
+			return op.Value, true
+		}
+	}
+	return nil, false
+}
+
+func TestMutate(t *testing.T) {
+	server := httptest.NewServer(NewHandler())
+	defer server.Close()
+
+	review := &AdmissionReview{Request: &AdmissionRequest{
+		UID:       "1",
+		Kind:      tidbKind,
+		Operation: "CREATE",
+		Object:    runtime.RawExtension{Raw: newTiDBJSON(`{"pd":{"replicas":5,"foo":"bar"},"tidb":{"service":{"annotations":{"a/b":"c"}}}}`)},
+	}}
+	reviewed := &AdmissionReview{}
+	postReview(t, server, MutatePath, review, reviewed)
+	resp := reviewed.Response
+	if resp == nil || !resp.Allowed || resp.UID != "1" {
+		t.Fatalf("Expected the TiDB to be allowed, got %+v", resp)
+	}
+	if resp.PatchType == nil || *resp.PatchType != patchTypeJSONPatch {
+		t.Fatalf("Expected a JSON patch, got %v", resp.PatchType)
+	}
+
+	var patch []patchOperation
+	if err := json.Unmarshal(resp.Patch, &patch); err != nil {
+		t.Fatalf("Failed to decode the patch: %v", err)
+	}
+	for _, op := range patch {
+		if op.Op != "add" {
+			t.Errorf("Expected only add operations, got %+v", op)
+		}
+	}
+	// The omitted spec of TiKV is added as a whole.
+	tikv, _ := getPatchValue(patch, "/spec/tikv")
+	if value, ok := tikv.(map[string]interface{}); !ok || value["replicas"] != float64(api.DefaultTiKVReplicas) {
+		t.Errorf("Expected the TiKV replicas to be defaulted to %d, got %v", api.DefaultTiKVReplicas, tikv)
+	}
+	if value, ok := getPatchValue(patch, "/spec/pd/storageSize"); !ok || value != api.DefaultPDStorageSize {
+		t.Errorf("Expected the PD storage size to be defaulted to %s, got %v", api.DefaultPDStorageSize, value)
+	}
+	for _, path := range []string{"/spec/pd/replicas", "/spec/pd/foo", "/spec/tidb/service/annotations/a~1b"} {
+		if _, ok := getPatchValue(patch, path); ok {
+			t.Errorf("Expected %s to be kept, got patch %+v", path, patch)
+		}
+	}
+}
+
+func TestMutateDefaulted(t *testing.T) {
+	tidb := &api.TiDB{
+		TypeMeta:   metav1.TypeMeta{APIVersion: api.SchemeGroupVersion.String(), Kind: api.ResourceKind},
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+	}
+	api.SetObjectDefaults_TiDB(tidb)
+	raw, err := json.Marshal(tidb)
+	if err != nil {
+		t.Fatalf("Failed to encode the TiDB: %v", err)
+	}
+
+	resp := mutate(&AdmissionRequest{Kind: tidbKind, Operation: "UPDATE", Object: runtime.RawExtension{Raw: raw}})
+	if !resp.Allowed || resp.Patch != nil || resp.PatchType != nil {
+		t.Errorf("Expected no patch for the defaulted TiDB, got %+v", resp)
+	}
+}
+
+func TestMutateInvalidObject(t *testing.T) {
+	resp := mutate(&AdmissionRequest{Kind: tidbKind, Operation: "CREATE", Object: runtime.RawExtension{Raw: []byte(`{`)}})
+	if resp.Allowed || resp.Result == nil {
+		t.Errorf("Expected the malformed TiDB to be denied, got %+v", resp)
+	}
+}
+
+func TestGenPatchEscape(t *testing.T) {
+	patch := genPatch("/spec", map[string]interface{}{}, map[string]interface{}{"a/b~c": "d"})
+	if len(patch) != 1 || patch[0].Path != "/spec/a~1b~0c" {
+		t.Errorf("Expected the key to be escaped, got %+v", patch)
+	}
+}
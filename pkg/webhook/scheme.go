@@ -0,0 +1,44 @@
+package webhook
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+
+	"github.com/gaocegege/kubetidb/pkg/apis/tidb/v1alpha1"
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+var (
+	scheme = runtime.NewScheme()
+	codecs = serializer.NewCodecFactory(scheme)
+)
+
+func init() {
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		panic(err)
+	}
+	if err := api.AddToScheme(scheme); err != nil {
+		panic(err)
+	}
+}
+
+// decodeTiDB decodes the TiDB of any version, sets the defaults and
+// converts it to v1beta1.
+func decodeTiDB(raw []byte) (*api.TiDB, error) {
+	obj, _, err := codecs.UniversalDeserializer().Decode(raw, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the TiDB: %v", err)
+	}
+	scheme.Default(obj)
+	converted, err := scheme.ConvertToVersion(obj, api.SchemeGroupVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert the TiDB: %v", err)
+	}
+	tidb, ok := converted.(*api.TiDB)
+	if !ok {
+		return nil, fmt.Errorf("expected TiDB but got %T", converted)
+	}
+	return tidb, nil
+}
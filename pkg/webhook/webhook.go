@@ -0,0 +1,157 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+	"github.com/gaocegege/kubetidb/pkg/apis/tidb/validation"
+)
+
+const (
+	// ValidatePath is the path of the validating admission webhook of TiDB
+	// clusters.
+	ValidatePath = "/validate"
+)
+
+// The types below are the AdmissionReview of admission.k8s.io/v1beta1, which
+// are not vendored. Only the fields used by the webhooks are declared.
+
+// AdmissionReview describes an admission review request and response.
+type AdmissionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *AdmissionRequest  `json:"request,omitempty"`
+	Response        *AdmissionResponse `json:"response,omitempty"`
+}
+
+// AdmissionRequest describes the admission attributes of the request.
+type AdmissionRequest struct {
+	UID       types.UID               `json:"uid"`
+	Kind      metav1.GroupVersionKind `json:"kind"`
+	Operation string                  `json:"operation"`
+	Object    runtime.RawExtension    `json:"object,omitempty"`
+	OldObject runtime.RawExtension    `json:"oldObject,omitempty"`
+}
+
+// AdmissionResponse describes an admission response.
+type AdmissionResponse struct {
+	UID       types.UID      `json:"uid"`
+	Allowed   bool           `json:"allowed"`
+	Result    *metav1.Status `json:"status,omitempty"`
+	Patch     []byte         `json:"patch,omitempty"`
+	PatchType *string        `json:"patchType,omitempty"`
+}
+
+// NewHandler returns the handler serving the admission and the conversion
+// webhooks.
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ValidatePath, serveValidate)
+	mux.HandleFunc(MutatePath, serveMutate)
+	mux.HandleFunc(ConvertPath, serveConvert)
+	return mux
+}
+
+// serveValidate writes back whether the TiDB in the admission review is
+// admitted.
+func serveValidate(w http.ResponseWriter, r *http.Request) {
+	serveReview(w, r, admit)
+}
+
+// serveReview decodes the admission review sent by the API server, and
+// writes back the response of the review.
+func serveReview(w http.ResponseWriter, r *http.Request, review func(*AdmissionRequest) *AdmissionResponse) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
+		http.Error(w, fmt.Sprintf("unsupported content type %q", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	admissionReview := &AdmissionReview{}
+	if err := json.Unmarshal(body, admissionReview); err != nil || admissionReview.Request == nil {
+		http.Error(w, fmt.Sprintf("failed to decode the admission review: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	admissionReview.Response = review(admissionReview.Request)
+	admissionReview.Request = nil
+	resp, err := json.Marshal(admissionReview)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(resp); err != nil {
+		glog.Errorf("Failed to write the admission review: %v", err)
+	}
+}
+
+// admit validates the TiDB in the admission request. The defaults are set
+// before the validation, so that the omitted fields are checked against the
+// values set by the mutating webhook. The TiDBs of all the versions are
+// validated as v1beta1.
+func admit(req *AdmissionRequest) *AdmissionResponse {
+	if req.Kind.Group != api.GroupName || req.Kind.Kind != api.ResourceKind {
+		return allowed(req.UID)
+	}
+
+	switch req.Operation {
+	case "CREATE":
+		tidb, err := decodeTiDB(req.Object.Raw)
+		if err != nil {
+			return denied(req.UID, metav1.StatusReasonBadRequest, err.Error())
+		}
+		if errs := validation.ValidateTiDB(tidb); len(errs) != 0 {
+			return denied(req.UID, metav1.StatusReasonInvalid, errs.ToAggregate().Error())
+		}
+	case "UPDATE":
+		tidb, err := decodeTiDB(req.Object.Raw)
+		if err != nil {
+			return denied(req.UID, metav1.StatusReasonBadRequest, err.Error())
+		}
+		oldTiDB, err := decodeTiDB(req.OldObject.Raw)
+		if err != nil {
+			return denied(req.UID, metav1.StatusReasonBadRequest, err.Error())
+		}
+		if errs := validation.ValidateTiDBUpdate(tidb, oldTiDB); len(errs) != 0 {
+			return denied(req.UID, metav1.StatusReasonInvalid, errs.ToAggregate().Error())
+		}
+	}
+	return allowed(req.UID)
+}
+
+func allowed(uid types.UID) *AdmissionResponse {
+	return &AdmissionResponse{UID: uid, Allowed: true}
+}
+
+func denied(uid types.UID, reason metav1.StatusReason, message string) *AdmissionResponse {
+	code := int32(http.StatusUnprocessableEntity)
+	if reason == metav1.StatusReasonBadRequest {
+		code = http.StatusBadRequest
+	}
+	return &AdmissionResponse{
+		UID:     uid,
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: message,
+			Reason:  reason,
+			Code:    code,
+		},
+	}
+}
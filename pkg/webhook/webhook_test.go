@@ -0,0 +1,277 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	api "github.com/gaocegege/kubetidb/pkg/apis/tidb/v1beta1"
+)
+
+var tidbKind = metav1.GroupVersionKind{Group: api.GroupName, Version: "v1beta1", Kind: api.ResourceKind}
+
+// newTiDBJSON returns a TiDB with the given spec in JSON.
+func newTiDBJSON(spec string) []byte {
+	return []byte(`{"apiVersion":"kubetidb.gaocegege.com/v1beta1","kind":"TiDB","metadata":{"name":"foo","namespace":"default"},"spec":` + spec + `}`)
+}
+
+// postReview posts the admission review to the webhook and returns the
+// reviewed one.
+func postReview(t *testing.T, server *httptest.Server, path string, review interface{}, reviewed interface{}) {
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatalf("Failed to encode the review: %v", err)
+	}
+	resp, err := http.Post(server.URL+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to post the review: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(reviewed); err != nil {
+		t.Fatalf("Failed to decode the review: %v", err)
+	}
+}
+
+func TestAdmitCreate(t *testing.T) {
+	server := httptest.NewServer(NewHandler())
+	defer server.Close()
+
+	testCases := []struct {
+		name    string
+		spec    string
+		allowed bool
+		message string
+	}{
+		{
+			name:    "defaults",
+			spec:    `{}`,
+			allowed: true,
+		},
+		{
+			name:    "even PD replicas",
+			spec:    `{"pd":{"replicas":2}}`,
+			message: "spec.pd.replicas",
+		},
+		{
+			name:    "no TiKV store",
+			spec:    `{"tikv":{"replicas":0}}`,
+			message: "spec.tikv.replicas",
+		},
+		{
+			name:    "too few TiKV stores",
+			spec:    `{"tikv":{"replicas":1}}`,
+			message: "spec.tikv.replicas: Invalid value: 1: must be greater than or equal to 3",
+		},
+		{
+			name:    "empty PD storage",
+			spec:    `{"pd":{"storageSize":"0"}}`,
+			message: "spec.pd.storageSize",
+		},
+		{
+			name:    "missing container",
+			spec:    `{"tidb":{"template":{"spec":{"containers":[{"name":"foo","image":"foo"}]}}}}`,
+			message: "must contain a container named tidb",
+		},
+	}
+	for _, tc := range testCases {
+		review := &AdmissionReview{Request: &AdmissionRequest{
+			UID:       "1",
+			Kind:      tidbKind,
+			Operation: "CREATE",
+			Object:    runtime.RawExtension{Raw: newTiDBJSON(tc.spec)},
+		}}
+		reviewed := &AdmissionReview{}
+		postReview(t, server, ValidatePath, review, reviewed)
+		resp := reviewed.Response
+		if resp == nil || resp.UID != "1" {
+			t.Errorf("%s: expected the response to the request, got %+v", tc.name, resp)
+			continue
+		}
+		if resp.Allowed != tc.allowed {
+			t.Errorf("%s: expected allowed %v, got %v", tc.name, tc.allowed, resp.Allowed)
+			continue
+		}
+		if tc.allowed {
+			continue
+		}
+		if resp.Result == nil || !strings.Contains(resp.Result.Message, tc.message) {
+			t.Errorf("%s: expected the message to contain %q, got %+v", tc.name, tc.message, resp.Result)
+			continue
+		}
+		if resp.Result.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expected code %d, got %d", tc.name, http.StatusUnprocessableEntity, resp.Result.Code)
+		}
+	}
+}
+
+func TestAdmitUpdate(t *testing.T) {
+	server := httptest.NewServer(NewHandler())
+	defer server.Close()
+
+	testCases := []struct {
+		name    string
+		oldSpec string
+		spec    string
+		allowed bool
+		message string
+	}{
+		{
+			name:    "scale PD out",
+			oldSpec: `{"pd":{"replicas":3}}`,
+			spec:    `{"pd":{"replicas":5}}`,
+			allowed: true,
+		},
+		{
+			name:    "scale PD in to the quorum",
+			oldSpec: `{"pd":{"replicas":5}}`,
+			spec:    `{"pd":{"replicas":3}}`,
+			allowed: true,
+		},
+		{
+			name:    "scale PD in below the quorum",
+			oldSpec: `{"pd":{"replicas":5}}`,
+			spec:    `{"pd":{"replicas":1}}`,
+			message: "must not be less than the quorum of the current members",
+		},
+		{
+			name:    "update a cluster with a single TiKV store",
+			oldSpec: `{"tikv":{"replicas":1}}`,
+			spec:    `{"tikv":{"replicas":1},"tidb":{"replicas":2}}`,
+			allowed: true,
+		},
+		{
+			name:    "scale a single TiKV store out",
+			oldSpec: `{"tikv":{"replicas":1}}`,
+			spec:    `{"tikv":{"replicas":3}}`,
+			allowed: true,
+		},
+		{
+			name:    "scale TiKV in below the minimum",
+			oldSpec: `{"tikv":{"replicas":3}}`,
+			spec:    `{"tikv":{"replicas":2}}`,
+			message: "spec.tikv.replicas",
+		},
+		{
+			name:    "scale a single TiKV store to two",
+			oldSpec: `{"tikv":{"replicas":1}}`,
+			spec:    `{"tikv":{"replicas":2}}`,
+			message: "spec.tikv.replicas",
+		},
+		{
+			name:    "change the PD storage class",
+			oldSpec: `{"pd":{"storageClassName":"ssd"}}`,
+			spec:    `{"pd":{"storageClassName":"hdd"}}`,
+			message: "spec.pd.storageClassName: Forbidden",
+		},
+		{
+			name:    "set the TiKV storage class",
+			oldSpec: `{}`,
+			spec:    `{"tikv":{"storageClassName":"ssd"}}`,
+			message: "spec.tikv.storageClassName: Forbidden",
+		},
+		{
+			name:    "change the TiKV storage size",
+			oldSpec: `{"tikv":{"storageSize":"10Gi"}}`,
+			spec:    `{"tikv":{"storageSize":"20Gi"}}`,
+			message: "spec.tikv.storageSize: Forbidden",
+		},
+	}
+	for _, tc := range testCases {
+		review := &AdmissionReview{Request: &AdmissionRequest{
+			Kind:      tidbKind,
+			Operation: "UPDATE",
+			Object:    runtime.RawExtension{Raw: newTiDBJSON(tc.spec)},
+			OldObject: runtime.RawExtension{Raw: newTiDBJSON(tc.oldSpec)},
+		}}
+		reviewed := &AdmissionReview{}
+		postReview(t, server, ValidatePath, review, reviewed)
+		resp := reviewed.Response
+		if resp == nil {
+			t.Errorf("%s: expected a response", tc.name)
+			continue
+		}
+		if resp.Allowed != tc.allowed {
+			t.Errorf("%s: expected allowed %v, got %v: %+v", tc.name, tc.allowed, resp.Allowed, resp.Result)
+			continue
+		}
+		if !tc.allowed && (resp.Result == nil || !strings.Contains(resp.Result.Message, tc.message)) {
+			t.Errorf("%s: expected the message to contain %q, got %+v", tc.name, tc.message, resp.Result)
+		}
+	}
+}
+
+func TestAdmitInvalidObject(t *testing.T) {
+	server := httptest.NewServer(NewHandler())
+	defer server.Close()
+
+	review := &AdmissionReview{Request: &AdmissionRequest{
+		Kind:      tidbKind,
+		Operation: "CREATE",
+		Object:    runtime.RawExtension{Raw: []byte(`{"apiVersion":"kubetidb.gaocegege.com/v1beta1","kind":"TiDB","spec":{"pd":{"replicas":"three"}}}`)},
+	}}
+	reviewed := &AdmissionReview{}
+	postReview(t, server, ValidatePath, review, reviewed)
+	if resp := reviewed.Response; resp == nil || resp.Allowed {
+		t.Fatalf("Expected the malformed TiDB to be denied, got %+v", resp)
+	}
+	if reviewed.Response.Result.Code != http.StatusBadRequest {
+		t.Errorf("Expected code %d, got %d", http.StatusBadRequest, reviewed.Response.Result.Code)
+	}
+}
+
+func TestAdmitOtherKinds(t *testing.T) {
+	server := httptest.NewServer(NewHandler())
+	defer server.Close()
+
+	review := &AdmissionReview{Request: &AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Operation: "CREATE",
+		Object:    runtime.RawExtension{Raw: []byte(`{}`)},
+	}}
+	reviewed := &AdmissionReview{}
+	postReview(t, server, ValidatePath, review, reviewed)
+	if resp := reviewed.Response; resp == nil || !resp.Allowed {
+		t.Errorf("Expected the other kinds to be allowed, got %+v", resp)
+	}
+}
+
+func TestServeValidateContentType(t *testing.T) {
+	server := httptest.NewServer(NewHandler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+ValidatePath, "text/plain", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("Failed to post the review: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status %d, got %d", http.StatusUnsupportedMediaType, resp.StatusCode)
+	}
+}
+
+func TestServeValidateWithoutRequest(t *testing.T) {
+	server := httptest.NewServer(NewHandler())
+	defer server.Close()
+
+	// The reviews of admission.k8s.io/v1alpha1 have no request, they are
+	// refused rather than allowed.
+	for _, path := range []string{ValidatePath, "/"} {
+		resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(`{"spec":{"operation":"CREATE"}}`))
+		if err != nil {
+			t.Fatalf("Failed to post the review: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Errorf("Expected the review without a request to %s to fail, got status %d", path, resp.StatusCode)
+		}
+	}
+}